@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+
+	"dlqt/internal/servicebus"
+)
+
+// Allowlist limits the namespaces and entities the API's identity is used against, whoever the caller is.
+// Both are glob patterns, a subscription is matched as topic/Subscriptions/subscription, a path no queue name can take,
+// and an empty list allows any valid name.
+type Allowlist struct {
+	Namespaces []string `json:"namespaces"`
+	Entities   []string `json:"entities"`
+}
+
+// Validate checks the patterns are well-formed
+func (a *Allowlist) Validate() error {
+	var errs []error
+	for _, pattern := range slices.Concat(a.Namespaces, a.Entities) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("pattern '%s': %w", pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Allows returns nil when the namespace and entity are on the allowlist, otherwise an error saying which isn't
+func (a *Allowlist) Allows(namespace string, entity servicebus.Entity) error {
+	if len(a.Namespaces) > 0 && !matchesAny(a.Namespaces, namespace) {
+		return fmt.Errorf("namespace '%s' is not on the allowlist", namespace)
+	}
+	if len(a.Entities) > 0 && !matchesAny(a.Entities, entityPath(entity)) {
+		return fmt.Errorf("%s is not on the allowlist", entity)
+	}
+	return nil
+}
+
+// ValidationMiddleware rejects requests whose namespace or entity are invalid Azure names or aren't on the allowlist,
+// before the API's identity is used against them
+func ValidationMiddleware(allowlist *Allowlist, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		if err := servicebus.ValidateNamespaceName(namespace); err != nil {
+			respondErrorMessage(w, http.StatusBadRequest, "invalid namespace", err.Error())
+			return
+		}
+		entity, err := entityFromQuery(r)
+		if err == nil {
+			err = entity.ValidateNames()
+		}
+		if err != nil {
+			respondErrorMessage(w, http.StatusBadRequest, "invalid entity", err.Error())
+			return
+		}
+
+		if err := allowlist.Allows(namespace, entity); err != nil {
+			slog.WarnContext(r.Context(), "allowlist rejected request", "namespace", namespace, "entity", entity.String())
+			respondErrorMessage(w, http.StatusForbidden, "not allowed", err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
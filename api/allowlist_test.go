@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationMiddleware(t *testing.T) {
+	allowlist := &Allowlist{Namespaces: []string{"sb-payments-*"}, Entities: []string{"orders", "events/Subscriptions/*"}}
+	handler := ValidationMiddleware(allowlist, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "Queue", query: "namespace=sb-payments-dev&queue=orders", wantStatus: http.StatusNoContent},
+		{name: "Subscription", query: "namespace=sb-payments-dev&topic=events&subscription=audit", wantStatus: http.StatusNoContent},
+		{name: "MissingNamespace", query: "queue=orders", wantStatus: http.StatusBadRequest, wantMessage: "a namespace must be set"},
+		{name: "NamespaceHost", query: "namespace=evil.example.com%2F&queue=orders", wantStatus: http.StatusBadRequest, wantMessage: "must be 6-50 letters"},
+		{name: "MissingQueue", query: "namespace=sb-payments-dev", wantStatus: http.StatusBadRequest, wantMessage: "a queue or a topic and subscription must be set"},
+		{name: "InvalidQueue", query: "namespace=sb-payments-dev&queue=orders%3F", wantStatus: http.StatusBadRequest, wantMessage: "queue 'orders?'"},
+		{name: "NamespaceNotAllowed", query: "namespace=sb-shipping&queue=orders", wantStatus: http.StatusForbidden, wantMessage: "namespace 'sb-shipping' is not on the allowlist"},
+		{name: "QueueWithTopicPrefix", query: "namespace=sb-payments-dev&queue=events%2Faudit", wantStatus: http.StatusForbidden, wantMessage: "queue 'events/audit' is not on the allowlist"},
+		{name: "QueueSubscriptionsSegment", query: "namespace=sb-payments-dev&queue=events%2FSubscriptions%2Faudit", wantStatus: http.StatusBadRequest, wantMessage: "must not contain a subscriptions segment"},
+		{name: "QueueNotAllowed", query: "namespace=sb-payments-dev&queue=refunds", wantStatus: http.StatusForbidden, wantMessage: "queue 'refunds' is not on the allowlist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fetch?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("expected %q in body, got %s", tt.wantMessage, rec.Body.String())
+			}
+		})
+	}
+}
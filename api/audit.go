@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// how long an audit event may take to reach every sink
+const auditTimeout = 10 * time.Second
+
+// audit outcomes, from the status the route responded with
+const (
+	AuditOutcomeSuccess  = "success"
+	AuditOutcomeDenied   = "denied"
+	AuditOutcomeRejected = "rejected"
+	AuditOutcomeFailure  = "failure"
+)
+
+// AuditConfig selects where audit events are written, any number of sinks can be enabled
+type AuditConfig struct {
+	// File is a JSON lines file events are appended to
+	File string `json:"file"`
+	// Stdout writes JSON lines to standard output
+	Stdout bool `json:"stdout"`
+	// Namespace and Queue name a Service Bus queue events are sent to, using the API's identity
+	Namespace string `json:"namespace"`
+	Queue     string `json:"queue"`
+}
+
+// Validate checks the audit queue is fully named
+func (c *AuditConfig) Validate() error {
+	if c.Namespace == "" && c.Queue == "" {
+		return nil
+	}
+	if err := servicebus.ValidateNamespaceName(c.Namespace); err != nil {
+		return fmt.Errorf("audit queue: %w", err)
+	}
+	if err := servicebus.QueueEntity(c.Queue).ValidateNames(); err != nil {
+		return fmt.Errorf("audit queue: %w", err)
+	}
+	return nil
+}
+
+// JSON-serializable record of one request against a dead letter queue
+type AuditEvent struct {
+	Time         time.Time      `json:"time"`
+	Route        string         `json:"route"`
+	Operation    string         `json:"operation"`
+	OID          string         `json:"oid,omitempty"`
+	UPN          string         `json:"upn,omitempty"`
+	AppID        string         `json:"appID,omitempty"`
+	Namespace    string         `json:"namespace"`
+	Queue        string         `json:"queue,omitempty"`
+	Topic        string         `json:"topic,omitempty"`
+	Subscription string         `json:"subscription,omitempty"`
+	Messages     []AuditMessage `json:"messages,omitempty"`
+	DryRun       bool           `json:"dryRun,omitempty"`
+	Status       int            `json:"status"`
+	Outcome      string         `json:"outcome"`
+}
+
+// JSON-serializable message a request read or retriggered, either field may be unknown
+type AuditMessage struct {
+	MessageID      string `json:"messageID,omitempty"`
+	SequenceNumber *int64 `json:"sequenceNumber,omitempty"`
+}
+
+// AuditSink stores audit events, implementations must be safe for concurrent use
+type AuditSink interface {
+	Write(ctx context.Context, event *AuditEvent) error
+	Close(ctx context.Context) error
+}
+
+// Auditor writes every audit event to all of its sinks
+type Auditor struct {
+	sinks []AuditSink
+}
+
+// NewAuditor opens the configured sinks, a config without sinks returns an auditor that drops events.
+// The audit queue's client comes from the pool.
+func NewAuditor(ctx context.Context, config *AuditConfig, pool *servicebus.ClientPool) (*Auditor, error) {
+	auditor := &Auditor{}
+	if config.File != "" {
+		f, err := os.OpenFile(config.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file: %w", err)
+		}
+		auditor.sinks = append(auditor.sinks, &jsonLinesSink{w: f, closer: f})
+	}
+	if config.Stdout {
+		auditor.sinks = append(auditor.sinks, &jsonLinesSink{w: os.Stdout})
+	}
+	if config.Queue != "" {
+		sink, err := newServiceBusSink(pool, config.Namespace, config.Queue)
+		if err != nil {
+			auditor.Close(ctx)
+			return nil, err
+		}
+		auditor.sinks = append(auditor.sinks, sink)
+	}
+	return auditor, nil
+}
+
+// Enabled reports whether events are written anywhere
+func (a *Auditor) Enabled() bool {
+	return a != nil && len(a.sinks) > 0
+}
+
+// Record writes the event to every sink, a failing sink is logged and doesn't stop the others
+func (a *Auditor) Record(ctx context.Context, event *AuditEvent) {
+	if a == nil {
+		return
+	}
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "failed to write audit event", "route", event.Route, "error", err)
+		}
+	}
+}
+
+// Close flushes and closes every sink
+func (a *Auditor) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range a.sinks {
+		errs = append(errs, sink.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// jsonLinesSink appends one JSON event per line
+type jsonLinesSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func (s *jsonLinesSink) Write(ctx context.Context, event *AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *jsonLinesSink) Close(ctx context.Context) error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// serviceBusSink sends each event as a JSON message to an audit queue
+type serviceBusSink struct {
+	release func()
+	sender  *azservicebus.Sender
+}
+
+func newServiceBusSink(pool *servicebus.ClientPool, namespace string, queue string) (*serviceBusSink, error) {
+	client, release, err := pool.Client(namespace + ".servicebus.windows.net")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit queue client: %w", err)
+	}
+	sender, err := client.NewSender(queue, nil)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to create sender for audit queue '%s': %w", queue, err)
+	}
+	return &serviceBusSink{release: release, sender: sender}, nil
+}
+
+func (s *serviceBusSink) Write(ctx context.Context, event *AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	return s.sender.SendMessage(ctx, &azservicebus.Message{
+		Body:        body,
+		ContentType: to.Ptr("application/json"),
+		Subject:     to.Ptr(event.Operation),
+	}, nil)
+}
+
+func (s *serviceBusSink) Close(ctx context.Context) error {
+	defer s.release()
+	return s.sender.Close(ctx)
+}
+
+type auditContextKey struct{}
+
+// auditEvent returns the event of the request being audited, handlers add the messages they touch to it.
+// Requests that aren't audited get a throwaway event.
+func auditEvent(ctx context.Context) *AuditEvent {
+	if event, ok := ctx.Value(auditContextKey{}).(*AuditEvent); ok {
+		return event
+	}
+	return &AuditEvent{}
+}
+
+// statusRecorder keeps the status a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// AuditMiddleware records an audit event for every authenticated request to the route, including requests
+// later middleware denies. It must run after AuthMiddleware, which provides the caller's identity.
+func AuditMiddleware(auditor *Auditor, route Route, next http.Handler) http.Handler {
+	if !auditor.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFromContext(r.Context())
+		query := r.URL.Query()
+		event := &AuditEvent{
+			Time:         time.Now().UTC(),
+			Route:        route.Path,
+			Operation:    route.Operation,
+			OID:          principal.ObjectID,
+			UPN:          principal.UPN,
+			AppID:        principal.AppID,
+			Namespace:    query.Get("namespace"),
+			Queue:        query.Get("queue"),
+			Topic:        query.Get("topic"),
+			Subscription: query.Get("subscription"),
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, event)))
+
+		event.Status = recorder.status
+		if event.Status == 0 {
+			event.Status = http.StatusOK
+		}
+		event.Outcome = auditOutcome(event.Status)
+
+		// the request may be cancelled once the response is written, the event is still recorded
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditTimeout)
+		defer cancel()
+		auditor.Record(ctx, event)
+	})
+}
+
+func auditOutcome(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return AuditOutcomeSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return AuditOutcomeDenied
+	case status < http.StatusInternalServerError:
+		return AuditOutcomeRejected
+	}
+	return AuditOutcomeFailure
+}
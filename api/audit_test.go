@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	claims := jwt.MapClaims{"oid": "user-oid", "preferred_username": "user@contoso.com", "azp": "cli-app-id"}
+
+	tests := []struct {
+		name        string
+		status      int
+		messages    []AuditMessage
+		wantOutcome string
+	}{
+		{name: "Success", status: http.StatusOK, messages: []AuditMessage{{MessageID: "message-1", SequenceNumber: to.Ptr[int64](7)}}, wantOutcome: AuditOutcomeSuccess},
+		{name: "Denied", status: http.StatusForbidden, wantOutcome: AuditOutcomeDenied},
+		{name: "Rejected", status: http.StatusBadRequest, wantOutcome: AuditOutcomeRejected},
+		{name: "Failure", status: http.StatusInternalServerError, wantOutcome: AuditOutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			auditor := &Auditor{sinks: []AuditSink{&jsonLinesSink{w: &buf}}}
+			route := Route{Path: "/retrigger", Operation: OperationRetrigger}
+			handler := AuditMiddleware(auditor, route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auditEvent(r.Context()).Messages = tt.messages
+				w.WriteHeader(tt.status)
+			}))
+
+			req := httptest.NewRequest(http.MethodPatch, "/retrigger?namespace=sb-dlqt&topic=events&subscription=audit", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(WithPrincipal(req.Context(), NewPrincipal(claims))))
+
+			var event AuditEvent
+			if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+				t.Fatalf("failed to parse audit event %q: %v", buf.String(), err)
+			}
+			if event.OID != "user-oid" || event.UPN != "user@contoso.com" || event.AppID != "cli-app-id" {
+				t.Errorf("expected caller from claims, got oid=%q upn=%q appID=%q", event.OID, event.UPN, event.AppID)
+			}
+			if event.Namespace != "sb-dlqt" || event.Topic != "events" || event.Subscription != "audit" || event.Operation != OperationRetrigger {
+				t.Errorf("expected request target and operation, got %+v", event)
+			}
+			if event.Status != tt.status || event.Outcome != tt.wantOutcome {
+				t.Errorf("expected status %d outcome %s, got %d %s", tt.status, tt.wantOutcome, event.Status, event.Outcome)
+			}
+			if len(event.Messages) != len(tt.messages) {
+				t.Errorf("expected %d messages, got %+v", len(tt.messages), event.Messages)
+			}
+			if event.Time.IsZero() {
+				t.Error("expected event time")
+			}
+		})
+	}
+
+}
+
+func TestAuditConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AuditConfig
+		wantErr bool
+	}{
+		{"Empty", AuditConfig{}, false},
+		{"FileAndStdout", AuditConfig{File: "audit.jsonl", Stdout: true}, false},
+		{"Queue", AuditConfig{Namespace: "sb-dlqt", Queue: "audit"}, false},
+		{"QueueWithoutNamespace", AuditConfig{Queue: "audit"}, true},
+		{"NamespaceWithoutQueue", AuditConfig{Namespace: "sb-dlqt"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
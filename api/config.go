@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dlqt/internal/logging"
+	"dlqt/internal/servicebus"
+)
+
+// Config is the API configuration, read from an optional JSON file and then overridden by environment variables
+type Config struct {
+	Auth AuthConfig `json:"auth"`
+	// PolicyFile restricts callers to namespaces, entities and operations, see Policy, empty allows everything
+	PolicyFile string `json:"policyFile"`
+	// RedactionFile masks sensitive message values in responses and logs, see redact.Rules, empty redacts nothing
+	RedactionFile string `json:"redactionFile"`
+	// Allowlist limits the namespaces and entities any caller can reach
+	Allowlist Allowlist `json:"allowlist"`
+	// Audit selects the sinks audit events are written to, none disables auditing
+	Audit AuditConfig `json:"audit"`
+	// ClientIdleTimeout closes a namespace's Service Bus connection once unused this long
+	ClientIdleTimeout Duration     `json:"clientIdleTimeout"`
+	Server            ServerConfig `json:"server"`
+	// Metrics exports the DLQ depth of entities as gauges
+	Metrics MetricsConfig `json:"metrics"`
+	Log     LogConfig     `json:"log"`
+}
+
+// LogConfig controls the API's log output
+type LogConfig struct {
+	// Level is debug, info, warn or error, info by default
+	Level string `json:"level"`
+	// Format is json or text, json by default
+	Format string `json:"format"`
+}
+
+// Options validates the config and converts it to logger options
+func (c *LogConfig) Options() (*logging.Options, error) {
+	options := &logging.Options{Format: logging.FormatJSON}
+	if c.Level != "" {
+		level, err := logging.ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		options.Level = level
+	}
+	if c.Format != "" {
+		format, err := logging.ParseFormat(c.Format)
+		if err != nil {
+			return nil, err
+		}
+		options.Format = format
+	}
+	return options, nil
+}
+
+// ServerConfig controls the HTTP server, zero durations use the defaults
+type ServerConfig struct {
+	// Address is the listen address, ":8080" by default
+	Address string `json:"address"`
+	// MetricsAddress is the listen address of /metrics, ":9090" by default. It is kept off Address so the metrics,
+	// which name namespaces and entities, aren't reachable through the public ingress.
+	MetricsAddress    string   `json:"metricsAddress"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	// WriteTimeout bounds a whole request, so it must allow for the longest bulk retrigger or stats scan
+	WriteTimeout Duration `json:"writeTimeout"`
+	IdleTimeout  Duration `json:"idleTimeout"`
+	// ShutdownTimeout is how long the API takes to stop after SIGTERM, including DrainDelay, it should be shorter
+	// than the platform's termination grace period
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	// DrainDelay is how long /readyz reports draining before the listener closes, at least one readiness probe
+	// period so the platform stops routing requests first
+	DrainDelay Duration `json:"drainDelay"`
+	// ReadinessNamespaces are probed by /readyz to check Service Bus is reachable
+	ReadinessNamespaces []string `json:"readinessNamespaces"`
+}
+
+// AuthConfig controls which access tokens the API accepts
+type AuthConfig struct {
+	// TenantIDs are the Entra tenants tokens may be issued by, each adds its issuer and JWKS URL unless those are set
+	TenantIDs []string `json:"tenantIDs"`
+	// Audiences are accepted aud claims, usually the API app registration's client ID
+	Audiences []string `json:"audiences"`
+	// Issuers are accepted iss claims
+	Issuers []string `json:"issuers"`
+	// JWKSURLs are the key sets token signatures are verified against
+	JWKSURLs []string `json:"jwksURLs"`
+	// Permissions overrides the permission of routes in the route table
+	Permissions map[string]Permission `json:"permissions"`
+	// JWKSRefreshInterval is how often keys are refreshed in the background
+	JWKSRefreshInterval Duration `json:"jwksRefreshInterval"`
+	// JWKSUnknownKIDInterval is the minimum time between refreshes triggered by tokens signed with an unknown key
+	JWKSUnknownKIDInterval Duration `json:"jwksUnknownKIDInterval"`
+}
+
+// Duration is a time.Duration written as a string like "1h" in the config file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+const (
+	defaultJWKSRefreshInterval    = time.Hour
+	defaultJWKSUnknownKIDInterval = 5 * time.Minute
+
+	defaultAddress           = ":8080"
+	defaultMetricsAddress    = ":9090"
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 5 * time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultShutdownTimeout   = 25 * time.Second
+	defaultDrainDelay        = 10 * time.Second
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// LoadConfig reads the file named by DLQT_API_CONFIG_FILE, applies the environment variable overrides and validates the result
+func LoadConfig() (*Config, error) {
+	config := &Config{}
+	if file := os.Getenv("DLQT_API_CONFIG_FILE"); file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		defer f.Close()
+		decoder := json.NewDecoder(f)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file '%s': %w", file, err)
+		}
+	}
+
+	if v := os.Getenv("DLQT_API_POLICY_FILE"); v != "" {
+		config.PolicyFile = v
+	}
+	if v := os.Getenv("DLQT_API_REDACTION_FILE"); v != "" {
+		config.RedactionFile = v
+	}
+	if v := os.Getenv("DLQT_API_ALLOWED_NAMESPACES"); v != "" {
+		config.Allowlist.Namespaces = splitList(v)
+	}
+	if v := os.Getenv("DLQT_API_ALLOWED_ENTITIES"); v != "" {
+		config.Allowlist.Entities = splitList(v)
+	}
+	if err := config.Allowlist.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid allowlist: %w", err)
+	}
+	if v := os.Getenv("DLQT_API_CLIENT_IDLE_TIMEOUT"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("DLQT_API_CLIENT_IDLE_TIMEOUT: %w", err)
+		}
+		config.ClientIdleTimeout = Duration(parsed)
+	}
+	if config.ClientIdleTimeout < 0 {
+		return nil, errors.New("client idle timeout must not be negative")
+	}
+	if err := config.Server.applyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	config.Server.applyDefaults()
+	if err := config.Server.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid server config: %w", err)
+	}
+	if v := os.Getenv("DLQT_API_METRICS_ENTITIES"); v != "" {
+		config.Metrics.Entities = splitList(v)
+	}
+	if v := os.Getenv("DLQT_API_METRICS_INTERVAL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("DLQT_API_METRICS_INTERVAL: %w", err)
+		}
+		config.Metrics.Interval = Duration(parsed)
+	}
+	if err := config.Metrics.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid metrics config: %w", err)
+	}
+	if v := os.Getenv("DLQT_API_LOG_LEVEL"); v != "" {
+		config.Log.Level = v
+	}
+	if v := os.Getenv("DLQT_API_LOG_FORMAT"); v != "" {
+		config.Log.Format = v
+	}
+	if _, err := config.Log.Options(); err != nil {
+		return nil, fmt.Errorf("invalid log config: %w", err)
+	}
+	if err := config.Audit.applyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	if err := config.Audit.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid audit config: %w", err)
+	}
+	if err := config.Auth.applyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	config.Auth.applyDefaults()
+	if err := config.Auth.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+	return config, nil
+}
+
+// applyEnv overrides fields with comma-separated environment variables
+func (c *AuthConfig) applyEnv(getenv func(string) string) error {
+	for name, field := range map[string]*[]string{
+		"DLQT_API_TENANT_IDS": &c.TenantIDs,
+		"DLQT_API_AUDIENCES":  &c.Audiences,
+		"DLQT_API_ISSUERS":    &c.Issuers,
+		"DLQT_API_JWKS_URLS":  &c.JWKSURLs,
+	} {
+		if v := getenv(name); v != "" {
+			*field = splitList(v)
+		}
+	}
+
+	// scopes and roles as /route=value pairs, e.g. /retrigger=dlq.admin, each overriding one field of the route's permission
+	for name, set := range map[string]func(*Permission, string){
+		"DLQT_API_SCOPES": func(p *Permission, scope string) { p.Scope = scope },
+		"DLQT_API_ROLES":  func(p *Permission, role string) { p.Role = role },
+	} {
+		v := getenv(name)
+		if v == "" {
+			continue
+		}
+		if c.Permissions == nil {
+			c.Permissions = map[string]Permission{}
+		}
+		for _, pair := range splitList(v) {
+			route, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%s entry '%s' must be /route=value", name, pair)
+			}
+			route = strings.TrimSpace(route)
+			permission, ok := c.Permissions[route]
+			if !ok {
+				permission, _ = defaultPermission(route)
+			}
+			set(&permission, strings.TrimSpace(value))
+			c.Permissions[route] = permission
+		}
+	}
+
+	for name, field := range map[string]*Duration{
+		"DLQT_API_JWKS_REFRESH_INTERVAL":     &c.JWKSRefreshInterval,
+		"DLQT_API_JWKS_UNKNOWN_KID_INTERVAL": &c.JWKSUnknownKIDInterval,
+	} {
+		if v := getenv(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = Duration(parsed)
+		}
+	}
+	return nil
+}
+
+// applyEnv overrides the listen address, timeouts and readiness namespaces with environment variables
+func (c *ServerConfig) applyEnv(getenv func(string) string) error {
+	if v := getenv("DLQT_API_ADDRESS"); v != "" {
+		c.Address = v
+	}
+	if v := getenv("DLQT_API_METRICS_ADDRESS"); v != "" {
+		c.MetricsAddress = v
+	}
+	if v := getenv("DLQT_API_READINESS_NAMESPACES"); v != "" {
+		c.ReadinessNamespaces = splitList(v)
+	}
+	for name, field := range map[string]*Duration{
+		"DLQT_API_READ_HEADER_TIMEOUT": &c.ReadHeaderTimeout,
+		"DLQT_API_READ_TIMEOUT":        &c.ReadTimeout,
+		"DLQT_API_WRITE_TIMEOUT":       &c.WriteTimeout,
+		"DLQT_API_IDLE_TIMEOUT":        &c.IdleTimeout,
+		"DLQT_API_SHUTDOWN_TIMEOUT":    &c.ShutdownTimeout,
+		"DLQT_API_DRAIN_DELAY":         &c.DrainDelay,
+	} {
+		if v := getenv(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = Duration(parsed)
+		}
+	}
+	return nil
+}
+
+func (c *ServerConfig) applyDefaults() {
+	if c.Address == "" {
+		c.Address = defaultAddress
+	}
+	if c.MetricsAddress == "" {
+		c.MetricsAddress = defaultMetricsAddress
+	}
+	for field, value := range map[*Duration]time.Duration{
+		&c.ReadHeaderTimeout: defaultReadHeaderTimeout,
+		&c.ReadTimeout:       defaultReadTimeout,
+		&c.WriteTimeout:      defaultWriteTimeout,
+		&c.IdleTimeout:       defaultIdleTimeout,
+		&c.ShutdownTimeout:   defaultShutdownTimeout,
+		&c.DrainDelay:        defaultDrainDelay,
+	} {
+		if *field == 0 {
+			*field = Duration(value)
+		}
+	}
+}
+
+// Validate reports every problem with the server config at once
+func (c *ServerConfig) Validate() error {
+	var errs []error
+	if c.ReadHeaderTimeout < 0 || c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.DrainDelay < 0 || c.DrainDelay >= c.ShutdownTimeout {
+		errs = append(errs, errors.New("drain delay must be shorter than the shutdown timeout, which includes it"))
+	}
+	if c.MetricsAddress == c.Address {
+		errs = append(errs, errors.New("metrics address must differ from the address, so metrics stay off the public listener"))
+	}
+	for _, namespace := range c.ReadinessNamespaces {
+		if err := servicebus.ValidateNamespaceName(namespace); err != nil {
+			errs = append(errs, fmt.Errorf("readiness namespace: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyEnv overrides the audit sinks with environment variables
+func (c *AuditConfig) applyEnv(getenv func(string) string) error {
+	if v := getenv("DLQT_API_AUDIT_FILE"); v != "" {
+		c.File = v
+	}
+	if v := getenv("DLQT_API_AUDIT_STDOUT"); v != "" {
+		stdout, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("DLQT_API_AUDIT_STDOUT must be true or false: %w", err)
+		}
+		c.Stdout = stdout
+	}
+	if v := getenv("DLQT_API_AUDIT_NAMESPACE"); v != "" {
+		c.Namespace = v
+	}
+	if v := getenv("DLQT_API_AUDIT_QUEUE"); v != "" {
+		c.Queue = v
+	}
+	return nil
+}
+
+// applyDefaults derives issuers and JWKS URLs from the tenants and fills in the route table's permissions
+func (c *AuthConfig) applyDefaults() {
+	if len(c.Issuers) == 0 {
+		for _, tenantID := range c.TenantIDs {
+			c.Issuers = append(c.Issuers, fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", tenantID))
+		}
+	}
+	if len(c.JWKSURLs) == 0 {
+		for _, tenantID := range c.TenantIDs {
+			c.JWKSURLs = append(c.JWKSURLs, fmt.Sprintf("https://login.microsoftonline.com/%s/discovery/v2.0/keys", tenantID))
+		}
+	}
+
+	if c.JWKSRefreshInterval == 0 {
+		c.JWKSRefreshInterval = Duration(defaultJWKSRefreshInterval)
+	}
+	if c.JWKSUnknownKIDInterval == 0 {
+		c.JWKSUnknownKIDInterval = Duration(defaultJWKSUnknownKIDInterval)
+	}
+
+	if c.Permissions == nil {
+		c.Permissions = map[string]Permission{}
+	}
+	for _, route := range routes {
+		if _, ok := c.Permissions[route.Path]; !ok {
+			c.Permissions[route.Path] = route.Permission
+		}
+	}
+}
+
+// Validate reports every problem with the config at once
+func (c *AuthConfig) Validate() error {
+	var errs []error
+	for _, tenantID := range c.TenantIDs {
+		if !tenantIDPattern.MatchString(tenantID) {
+			errs = append(errs, fmt.Errorf("tenant ID '%s' is not a GUID", tenantID))
+		}
+	}
+	if len(c.Audiences) == 0 {
+		errs = append(errs, errors.New("at least one audience is required"))
+	}
+	if len(c.Issuers) == 0 {
+		errs = append(errs, errors.New("at least one tenant ID or issuer is required"))
+	}
+	for _, issuer := range c.Issuers {
+		if err := validateHTTPS(issuer); err != nil {
+			errs = append(errs, fmt.Errorf("issuer '%s': %w", issuer, err))
+		}
+	}
+	if len(c.JWKSURLs) == 0 {
+		errs = append(errs, errors.New("at least one tenant ID or JWKS URL is required"))
+	}
+	for _, jwksURL := range c.JWKSURLs {
+		if err := validateHTTPS(jwksURL); err != nil {
+			errs = append(errs, fmt.Errorf("JWKS URL '%s': %w", jwksURL, err))
+		}
+	}
+	if c.JWKSRefreshInterval < 0 || c.JWKSUnknownKIDInterval < 0 {
+		errs = append(errs, errors.New("JWKS intervals must not be negative"))
+	}
+	for route, permission := range c.Permissions {
+		if _, ok := defaultPermission(route); !ok {
+			errs = append(errs, fmt.Errorf("route '%s' is not an API route", route))
+		}
+		if permission.Scope == "" && permission.Role == "" {
+			errs = append(errs, fmt.Errorf("route '%s' needs a scope or a role", route))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateHTTPS(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an absolute https URL")
+	}
+	return nil
+}
+
+func splitList(v string) []string {
+	var values []string
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"dlqt/internal/logging"
+)
+
+const testTenantID = "f09f69e2-b684-4c08-9195-f8f10f54154c"
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("DLQT_API_TENANT_IDS", testTenantID+", 11111111-2222-3333-4444-555555555555")
+		t.Setenv("DLQT_API_AUDIENCES", "api-client-id")
+		t.Setenv("DLQT_API_SCOPES", "/retrigger=dlq.admin")
+		t.Setenv("DLQT_API_ROLES", "/fetch=dlq.reader")
+
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		wantIssuers := []string{
+			"https://login.microsoftonline.com/" + testTenantID + "/v2.0",
+			"https://login.microsoftonline.com/11111111-2222-3333-4444-555555555555/v2.0",
+		}
+		if !slices.Equal(config.Auth.Issuers, wantIssuers) {
+			t.Errorf("expected issuers %v, got %v", wantIssuers, config.Auth.Issuers)
+		}
+		if len(config.Auth.JWKSURLs) != 2 {
+			t.Errorf("expected a JWKS URL per tenant, got %v", config.Auth.JWKSURLs)
+		}
+		if want := (Permission{Scope: "dlq.admin", Role: "dlq.retrigger"}); config.Auth.Permissions["/retrigger"] != want {
+			t.Errorf("expected /retrigger permission %+v, got %+v", want, config.Auth.Permissions["/retrigger"])
+		}
+		if want := (Permission{Scope: "dlq.read", Role: "dlq.reader"}); config.Auth.Permissions["/fetch"] != want {
+			t.Errorf("expected /fetch permission %+v, got %+v", want, config.Auth.Permissions["/fetch"])
+		}
+		if want := (Permission{Scope: "dlq.read", Role: "dlq.read"}); config.Auth.Permissions["/messages"] != want {
+			t.Errorf("expected default /messages permission %+v, got %+v", want, config.Auth.Permissions["/messages"])
+		}
+		if config.Server.Address != ":8080" || config.Server.MetricsAddress != ":9090" || config.Server.WriteTimeout != Duration(defaultWriteTimeout) {
+			t.Errorf("expected default server config, got %+v", config.Server)
+		}
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		t.Setenv("DLQT_API_TENANT_IDS", testTenantID)
+		t.Setenv("DLQT_API_AUDIENCES", "api-client-id")
+		t.Setenv("DLQT_API_ADDRESS", "127.0.0.1:9090")
+		t.Setenv("DLQT_API_METRICS_ADDRESS", "127.0.0.1:9091")
+		t.Setenv("DLQT_API_SHUTDOWN_TIMEOUT", "10s")
+		t.Setenv("DLQT_API_DRAIN_DELAY", "5s")
+		t.Setenv("DLQT_API_READINESS_NAMESPACES", "sb-dlqt")
+
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if config.Server.Address != "127.0.0.1:9090" || config.Server.MetricsAddress != "127.0.0.1:9091" || config.Server.ShutdownTimeout != Duration(10*time.Second) || config.Server.DrainDelay != Duration(5*time.Second) {
+			t.Errorf("expected server config from env, got %+v", config.Server)
+		}
+		if !slices.Equal(config.Server.ReadinessNamespaces, []string{"sb-dlqt"}) {
+			t.Errorf("expected readiness namespaces from env, got %v", config.Server.ReadinessNamespaces)
+		}
+
+		t.Setenv("DLQT_API_DRAIN_DELAY", "10s")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected error for a drain delay as long as the shutdown timeout")
+		}
+
+		t.Setenv("DLQT_API_DRAIN_DELAY", "5s")
+		t.Setenv("DLQT_API_METRICS_ADDRESS", "127.0.0.1:9090")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected error for metrics served on the API address")
+		}
+
+		t.Setenv("DLQT_API_METRICS_ADDRESS", "127.0.0.1:9091")
+		t.Setenv("DLQT_API_READINESS_NAMESPACES", "sb-dlqt.servicebus.windows.net")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected error for a readiness namespace host name")
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		data := `{"auth": {"audiences": ["from-file"], "issuers": ["https://issuer.example.com"], "jwksURLs": ["https://issuer.example.com/keys"], "permissions": {"/stats": {"role": "dlq.stats"}}}}`
+		if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		t.Setenv("DLQT_API_CONFIG_FILE", file)
+		t.Setenv("DLQT_API_AUDIENCES", "from-env")
+
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if !slices.Equal(config.Auth.Audiences, []string{"from-env"}) {
+			t.Errorf("expected env to override file audiences, got %v", config.Auth.Audiences)
+		}
+		if !slices.Equal(config.Auth.Issuers, []string{"https://issuer.example.com"}) {
+			t.Errorf("expected file issuers, got %v", config.Auth.Issuers)
+		}
+		if want := (Permission{Role: "dlq.stats"}); config.Auth.Permissions["/stats"] != want {
+			t.Errorf("expected file /stats permission %+v, got %+v", want, config.Auth.Permissions["/stats"])
+		}
+	})
+
+	t.Run("Allowlist", func(t *testing.T) {
+		t.Setenv("DLQT_API_TENANT_IDS", testTenantID)
+		t.Setenv("DLQT_API_AUDIENCES", "api-client-id")
+		t.Setenv("DLQT_API_ALLOWED_NAMESPACES", "sb-dlqt, sb-payments-*")
+		t.Setenv("DLQT_API_ALLOWED_ENTITIES", "[")
+
+		if _, err := LoadConfig(); err == nil {
+			t.Fatal("expected error for invalid entity pattern")
+		}
+
+		t.Setenv("DLQT_API_ALLOWED_ENTITIES", "orders, events/Subscriptions/*")
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if !slices.Equal(config.Allowlist.Namespaces, []string{"sb-dlqt", "sb-payments-*"}) {
+			t.Errorf("expected allowed namespaces from env, got %v", config.Allowlist.Namespaces)
+		}
+		if !slices.Equal(config.Allowlist.Entities, []string{"orders", "events/Subscriptions/*"}) {
+			t.Errorf("expected allowed entities from env, got %v", config.Allowlist.Entities)
+		}
+	})
+
+	t.Run("Log", func(t *testing.T) {
+		t.Setenv("DLQT_API_TENANT_IDS", testTenantID)
+		t.Setenv("DLQT_API_AUDIENCES", "api-client-id")
+		t.Setenv("DLQT_API_LOG_LEVEL", "debug")
+
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		options, err := config.Log.Options()
+		if err != nil {
+			t.Fatalf("failed to get log options: %v", err)
+		}
+		if options.Level != slog.LevelDebug || options.Format != logging.FormatJSON {
+			t.Errorf("expected debug JSON logs, got %+v", options)
+		}
+
+		t.Setenv("DLQT_API_LOG_FORMAT", "logfmt")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected error for unknown log format")
+		}
+	})
+
+	t.Run("UnknownFileField", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(file, []byte(`{"auth": {"audience": "typo"}}`), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		t.Setenv("DLQT_API_CONFIG_FILE", file)
+
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected error for unknown field")
+		}
+	})
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	valid := func() AuthConfig {
+		config := AuthConfig{TenantIDs: []string{testTenantID}, Audiences: []string{"api-client-id"}}
+		config.applyDefaults()
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*AuthConfig)
+		valid  bool
+	}{
+		{name: "Valid", modify: func(c *AuthConfig) {}, valid: true},
+		{name: "NoAudience", modify: func(c *AuthConfig) { c.Audiences = nil }},
+		{name: "BadTenant", modify: func(c *AuthConfig) { c.TenantIDs = []string{"contoso"} }},
+		{name: "NoIssuer", modify: func(c *AuthConfig) { c.Issuers = nil }},
+		{name: "HTTPJWKS", modify: func(c *AuthConfig) { c.JWKSURLs = []string{"http://keys.example.com"} }},
+		{name: "RoleOnly", modify: func(c *AuthConfig) { c.Permissions["/fetch"] = Permission{Role: "dlq.read"} }, valid: true},
+		{name: "EmptyPermission", modify: func(c *AuthConfig) { c.Permissions["/fetch"] = Permission{} }},
+		{name: "UnknownRoute", modify: func(c *AuthConfig) { c.Permissions["/admin"] = Permission{Scope: "dlq.read"} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(&config)
+			if err := config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"dlqt/internal/decode"
+)
+
+// decoders renders message bodies for requests with a decoder query parameter, see loadDecoders
+var decoders = decode.NewDefaultRegistry()
+
+// loadDecoders adds the protobuf and avro decoders configured by environment variables
+func loadDecoders() error {
+	registry, err := decode.NewConfiguredRegistry(decode.Config{
+		ProtobufDescriptorSet: os.Getenv("DLQT_PROTOBUF_DESCRIPTOR_SET"),
+		ProtobufMessage:       os.Getenv("DLQT_PROTOBUF_MESSAGE"),
+		AvroSchema:            os.Getenv("DLQT_AVRO_SCHEMA"),
+	})
+	if err != nil {
+		return err
+	}
+	decoders = registry
+	return nil
+}
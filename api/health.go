@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dlqt/internal/servicebus"
+)
+
+// how long each readiness check may take
+const readinessCheckTimeout = 5 * time.Second
+
+// JSON-serializable result of a health check, with the outcome of each readiness check
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Health serves the liveness and readiness endpoints, which bypass auth so the platform can probe them
+type Health struct {
+	keys *JWKSCache
+	// namespaces are probed for readiness through the API's identity
+	namespaces []string
+	clients    *servicebus.ClientPool
+	draining   atomic.Bool
+}
+
+// NewHealth creates the health endpoints, readiness requires signing keys and every namespace to be reachable
+func NewHealth(keys *JWKSCache, namespaces []string, clients *servicebus.ClientPool) *Health {
+	return &Health{keys: keys, namespaces: namespaces, clients: clients}
+}
+
+// Drain marks the API not ready, so the platform stops routing requests to it while in-flight requests finish
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Liveness reports the process is serving requests
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondHealth(w, http.StatusOK, &HealthResponse{Status: "ok"})
+}
+
+// Readiness reports whether requests can be served: signing keys are loaded and Service Bus is reachable
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		respondHealth(w, http.StatusServiceUnavailable, &HealthResponse{Status: "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	response := &HealthResponse{Status: "ready", Checks: map[string]string{"jwks": "ok"}}
+	if !h.keys.Ready(ctx) {
+		response.Checks["jwks"] = "no signing keys loaded"
+	}
+
+	// probe namespaces concurrently so one slow namespace doesn't time out the others
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, namespace := range h.namespaces {
+		wg.Go(func() {
+			result := "ok"
+			if err := h.checkNamespace(ctx, namespace); err != nil {
+				slog.WarnContext(ctx, "readiness check failed", "namespace", namespace, "error", err)
+				result = "unreachable"
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[namespace] = result
+		})
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for _, result := range response.Checks {
+		if result != "ok" {
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	respondHealth(w, status, response)
+}
+
+// checkNamespace reads the namespace's properties, which needs the namespace to resolve and the identity to get a token
+func (h *Health) checkNamespace(ctx context.Context, namespace string) error {
+	adminClient, err := h.clients.AdminClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		return err
+	}
+	_, err = adminClient.GetNamespaceProperties(ctx, nil)
+	return err
+}
+
+func respondHealth(w http.ResponseWriter, status int, response *HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
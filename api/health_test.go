@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	readiness := func(health *Health) (int, *HealthResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		health.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var response HealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse readiness response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, &response
+	}
+
+	t.Run("Liveness", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewHealth(nil, nil, nil).Liveness(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+		health := NewHealth(jwks.cache(ctx, time.Hour, time.Hour), nil, nil)
+
+		status, response := readiness(health)
+		if status != http.StatusOK || response.Status != "ready" || response.Checks["jwks"] != "ok" {
+			t.Errorf("expected ready, got %d %+v", status, response)
+		}
+	})
+
+	t.Run("NoSigningKeys", func(t *testing.T) {
+		jwks := newTestJWKS(t)
+		jwks.setDown(true)
+		health := NewHealth(jwks.cache(ctx, time.Hour, time.Hour), nil, nil)
+
+		status, response := readiness(health)
+		if status != http.StatusServiceUnavailable || response.Checks["jwks"] == "ok" {
+			t.Errorf("expected not ready without signing keys, got %d %+v", status, response)
+		}
+	})
+
+	t.Run("Draining", func(t *testing.T) {
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+		health := NewHealth(jwks.cache(ctx, time.Hour, time.Hour), nil, nil)
+		health.Drain()
+
+		status, response := readiness(health)
+		if status != http.StatusServiceUnavailable || response.Status != "draining" {
+			t.Errorf("expected draining, got %d %+v", status, response)
+		}
+	})
+}
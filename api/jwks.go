@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"golang.org/x/time/rate"
+)
+
+const (
+	jwksHTTPTimeout = 10 * time.Second
+	// jwksRateLimitWaitMax bounds how long a request with an unknown key waits for the refresh rate limiter
+	jwksRateLimitWaitMax = 5 * time.Second
+)
+
+// JWKSCache holds the signing keys of the configured JWKS URLs for the lifetime of the API,
+// refreshing them in the background and when a token is signed with an unknown key
+type JWKSCache struct {
+	keyfunc keyfunc.Keyfunc
+	storage jwkset.Storage
+}
+
+// NewJWKSCache loads the keys and starts the background refresh, which stops when ctx is done.
+// A failed first load doesn't fail startup, Ready reports false until keys are loaded. A nil client uses a default.
+func NewJWKSCache(ctx context.Context, config *AuthConfig, client *http.Client) (*JWKSCache, error) {
+	if client == nil {
+		client = &http.Client{Timeout: jwksHTTPTimeout}
+	}
+
+	storages := make(map[string]jwkset.Storage, len(config.JWKSURLs))
+	for _, jwksURL := range config.JWKSURLs {
+		storage, err := jwkset.NewStorageFromHTTP(jwksURL, jwkset.HTTPClientStorageOptions{
+			Client:                    client,
+			Ctx:                       ctx,
+			HTTPTimeout:               jwksHTTPTimeout,
+			NoErrorReturnFirstHTTPReq: true,
+			RefreshInterval:           time.Duration(config.JWKSRefreshInterval),
+			RefreshErrorHandler: func(ctx context.Context, err error) {
+				slog.Warn("failed to refresh JWKS", "url", jwksURL, "error", err)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JWKS storage for '%s': %w", jwksURL, err)
+		}
+		storages[jwksURL] = storage
+	}
+
+	storage, err := jwkset.NewHTTPClient(jwkset.HTTPClientOptions{
+		HTTPURLs:          storages,
+		PrioritizeHTTP:    true,
+		RateLimitWaitMax:  jwksRateLimitWaitMax,
+		RefreshUnknownKID: rate.NewLimiter(rate.Every(time.Duration(config.JWKSUnknownKIDInterval)), 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS client: %w", err)
+	}
+
+	k, err := keyfunc.New(keyfunc.Options{Ctx: ctx, Storage: storage})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keyfunc: %w", err)
+	}
+	return &JWKSCache{keyfunc: k, storage: storage}, nil
+}
+
+// Keyfunc verifies token signatures against the cached keys
+func (c *JWKSCache) Keyfunc() keyfunc.Keyfunc {
+	return c.keyfunc
+}
+
+// Ready reports whether any signing keys have been loaded
+func (c *JWKSCache) Ready(ctx context.Context) bool {
+	keys, err := c.storage.KeyReadAll(ctx)
+	return err == nil && len(keys) > 0
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testJWKS is a stand-in JWKS endpoint whose signing keys can be rotated during a test
+type testJWKS struct {
+	t        *testing.T
+	server   *httptest.Server
+	requests atomic.Int32
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+	down bool
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	t.Helper()
+
+	j := &testJWKS{t: t, keys: map[string]*rsa.PrivateKey{}}
+	j.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j.requests.Add(1)
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		if j.down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		set := jwkset.JWKSMarshal{Keys: []jwkset.JWKMarshal{}}
+		for kid, key := range j.keys {
+			jwk, err := jwkset.NewJWKFromKey(&key.PublicKey, jwkset.JWKOptions{
+				Metadata: jwkset.JWKMetadataOptions{KID: kid, ALG: jwkset.AlgRS256, USE: jwkset.UseSig},
+			})
+			if err != nil {
+				t.Errorf("failed to create JWK: %v", err)
+				return
+			}
+			set.Keys = append(set.Keys, jwk.Marshal())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(j.server.Close)
+	return j
+}
+
+// addKey generates a signing key under a key ID
+func (j *testJWKS) addKey(kid string) {
+	j.t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		j.t.Fatalf("failed to generate key: %v", err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys[kid] = key
+}
+
+func (j *testJWKS) setDown(down bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.down = down
+}
+
+// sign creates a token signed with the key of a key ID
+func (j *testJWKS) sign(kid string, claims jwt.MapClaims) string {
+	j.t.Helper()
+
+	j.mu.Lock()
+	key := j.keys[kid]
+	j.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		j.t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+// cache creates a JWKS cache for the stand-in server
+func (j *testJWKS) cache(ctx context.Context, refresh, unknownKID time.Duration) *JWKSCache {
+	j.t.Helper()
+
+	config := &AuthConfig{
+		JWKSURLs:               []string{j.server.URL},
+		JWKSRefreshInterval:    Duration(refresh),
+		JWKSUnknownKIDInterval: Duration(unknownKID),
+	}
+	cache, err := NewJWKSCache(ctx, config, j.server.Client())
+	if err != nil {
+		j.t.Fatalf("failed to create JWKS cache: %v", err)
+	}
+	return cache
+}
+
+func TestJWKSCache(t *testing.T) {
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+
+	t.Run("CachesKeys", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+
+		cache := jwks.cache(ctx, time.Hour, time.Hour)
+		if !cache.Ready(ctx) {
+			t.Fatal("expected cache to be ready")
+		}
+		for range 3 {
+			if _, err := jwt.Parse(jwks.sign("key-1", claims), cache.Keyfunc().Keyfunc); err != nil {
+				t.Fatalf("failed to validate token: %v", err)
+			}
+		}
+		if got := jwks.requests.Load(); got != 1 {
+			t.Errorf("expected 1 JWKS request, got %d", got)
+		}
+	})
+
+	t.Run("NotReadyUntilLoaded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+		jwks.setDown(true)
+
+		cache := jwks.cache(ctx, time.Hour, time.Millisecond)
+		if cache.Ready(ctx) {
+			t.Fatal("expected cache not to be ready")
+		}
+
+		// a token with a key the cache doesn't know yet triggers a refresh
+		jwks.setDown(false)
+		if _, err := jwt.Parse(jwks.sign("key-1", claims), cache.Keyfunc().Keyfunc); err != nil {
+			t.Fatalf("failed to validate token: %v", err)
+		}
+		if !cache.Ready(ctx) {
+			t.Error("expected cache to be ready after refresh")
+		}
+	})
+
+	t.Run("RateLimitsUnknownKID", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+
+		cache := jwks.cache(ctx, time.Hour, time.Hour)
+		jwks.addKey("key-2")
+		jwks.addKey("key-3")
+
+		// the first unknown key refreshes, picking up both new keys
+		if _, err := jwt.Parse(jwks.sign("key-2", claims), cache.Keyfunc().Keyfunc); err != nil {
+			t.Fatalf("failed to validate token: %v", err)
+		}
+		if _, err := jwt.Parse(jwks.sign("key-3", claims), cache.Keyfunc().Keyfunc); err != nil {
+			t.Fatalf("failed to validate token: %v", err)
+		}
+
+		// another unknown key inside the interval is rejected without a refresh
+		jwks.addKey("key-4")
+		if _, err := jwt.Parse(jwks.sign("key-4", claims), cache.Keyfunc().Keyfunc); err == nil {
+			t.Error("expected rate-limited unknown key to be rejected")
+		}
+		if got := jwks.requests.Load(); got != 2 {
+			t.Errorf("expected 2 JWKS requests, got %d", got)
+		}
+	})
+
+	t.Run("RefreshesInBackground", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jwks := newTestJWKS(t)
+		jwks.addKey("key-1")
+
+		cache := jwks.cache(ctx, 20*time.Millisecond, time.Hour)
+		jwks.addKey("key-2")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for jwks.requests.Load() < 3 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if _, err := cache.storage.KeyRead(ctx, "key-2"); err != nil {
+			t.Errorf("expected background refresh to load key-2: %v", err)
+		}
+	})
+}
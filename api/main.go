@@ -1,18 +1,171 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dlqt/internal/logging"
+	"dlqt/internal/redact"
+	"dlqt/internal/servicebus"
+	"dlqt/internal/telemetry"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// clients shares one Service Bus connection per namespace between requests
+var clients *servicebus.ClientPool
+
+// redactor masks sensitive values in messages returned to callers and in logs, nil when no rules are configured
+var redactor *redact.Redactor
+
 func main() {
-	log.Println("starting DLQT API")
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatal("failed to load config: ", err)
+	}
+	logOptions, err := config.Log.Options()
+	if err != nil {
+		log.Fatal("invalid log config: ", err)
+	}
+	redactor, err = redact.Load(config.RedactionFile)
+	if err != nil {
+		log.Fatal("failed to load redaction rules: ", err)
+	}
+	logOptions.Redactor = redactor
+	logging.Setup(os.Stderr, logOptions)
+	slog.Info("starting DLQT API")
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), "dlqt-api", "")
+	if err != nil {
+		fatal("failed to set up telemetry", err)
+	}
+	if !telemetry.Enabled() {
+		slog.Info("no OTLP endpoint configured, traces are not exported")
+	}
+	keys, err := NewJWKSCache(context.Background(), &config.Auth, nil)
+	if err != nil {
+		fatal("failed to create JWKS cache", err)
+	}
+	if !keys.Ready(context.Background()) {
+		slog.Warn("no signing keys loaded yet, requests are rejected until a JWKS refresh succeeds")
+	}
+	policy, err := LoadPolicy(config.PolicyFile)
+	if err != nil {
+		fatal("failed to load policy", err)
+	}
+	if policy == nil {
+		slog.Info("no policy file configured, callers with the required scope or role can access any namespace and entity")
+	}
+	if !redactor.Enabled() {
+		slog.Info("no redaction rules configured, message bodies and properties are returned unmasked")
+	}
+	if len(config.Allowlist.Namespaces) == 0 {
+		slog.Info("no namespace allowlist configured, requests can target any Service Bus namespace")
+	}
+	clients = servicebus.NewClientPool(&servicebus.ClientPoolOptions{IdleTimeout: time.Duration(config.ClientIdleTimeout)})
 
-	http.Handle("/fetch", AuthMiddleware(http.HandlerFunc(fetchHandler)))
-	http.Handle("/retrigger", AuthMiddleware(http.HandlerFunc(retriggerHandler)))
+	auditor, err := NewAuditor(context.Background(), &config.Audit, clients)
+	if err != nil {
+		fatal("failed to create auditor", err)
+	}
+	if !auditor.Enabled() {
+		slog.Info("no audit sink configured, DLQ actions are not audited")
+	}
+	if err := loadDecoders(); err != nil {
+		fatal("failed to load decoders", err)
+	}
 
-	log.Println("server starting on port 8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal("failed to start server:", err)
+	mux := http.NewServeMux()
+	// middleware runs outermost first: tracing, metrics, auth, audit, validation, policy
+	for _, route := range routes {
+		var handler http.Handler = route.Handler
+		handler = PolicyMiddleware(policy, route.Operation, handler)
+		handler = ValidationMiddleware(&config.Allowlist, handler)
+		handler = AuditMiddleware(auditor, route, handler)
+		handler = AuthMiddleware(&config.Auth, keys, handler)
+		handler = MetricsMiddleware(route, handler)
+		handler = otelhttp.NewHandler(handler, route.Path)
+		mux.Handle(route.Path, handler)
 	}
+	health := NewHealth(keys, config.Server.ReadinessNamespaces, clients)
+	mux.HandleFunc("/healthz", health.Liveness)
+	mux.HandleFunc("/readyz", health.Readiness)
+
+	// metrics are served on their own listener, which the ingress doesn't expose
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", MetricsHandler())
+	metricsServer := &http.Server{
+		Addr:              config.Server.MetricsAddress,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: time.Duration(config.Server.ReadHeaderTimeout),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	server := &http.Server{
+		Addr:              config.Server.Address,
+		Handler:           RequestIDMiddleware(mux),
+		ReadHeaderTimeout: time.Duration(config.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(config.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(config.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(config.Server.IdleTimeout),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if len(config.Metrics.Entities) > 0 {
+		go refreshDepth(ctx, &config.Metrics)
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("server starting", "address", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+	go func() {
+		slog.Info("metrics server starting", "address", metricsServer.Addr)
+		serveErr <- metricsServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("failed to start server", err)
+	case <-ctx.Done():
+	}
+
+	// report draining until the readiness probe has seen it and the platform stops routing requests here, then stop
+	// accepting requests and let in-flight retriggers finish settling before closing the clients they use
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Server.ShutdownTimeout))
+	defer cancel()
+	slog.Info("shutting down, draining before closing the listener", "drainDelay", time.Duration(config.Server.DrainDelay), "timeout", time.Duration(config.Server.ShutdownTimeout))
+	health.Drain()
+	time.Sleep(time.Duration(config.Server.DrainDelay))
+	slog.Info("waiting for in-flight requests")
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to drain in-flight requests", "error", err)
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to stop metrics server", "error", err)
+	}
+	if err := auditor.Close(shutdownCtx); err != nil {
+		slog.Error("failed to close audit sinks", "error", err)
+	}
+	if err := clients.Close(shutdownCtx); err != nil {
+		slog.Error("failed to close Service Bus clients", "error", err)
+	}
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// fatal logs the error and exits, like log.Fatal but through the structured logger
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
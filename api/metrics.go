@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// default interval DLQ depth gauges are refreshed at
+const defaultDepthInterval = time.Minute
+
+// metricsRegistry holds the API's metrics, served unauthenticated on /metrics of the internal metrics address
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dlqt_api_requests_total",
+		Help: "API requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	requestDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dlqt_api_request_duration_seconds",
+		Help:    "API request latency by route, method and status.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"route", "method", "status"})
+	authFailuresTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dlqt_api_auth_failures_total",
+		Help: "Requests rejected by authentication or authorization, by reason.",
+	}, []string{"reason"})
+	operationsTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dlqt_api_operations_total",
+		Help: "Service Bus operations by operation and outcome.",
+	}, []string{"operation", "outcome"})
+	operationDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dlqt_api_operation_duration_seconds",
+		Help:    "Service Bus operation latency by operation.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"operation"})
+	serviceBusErrorsTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dlqt_api_servicebus_errors_total",
+		Help: "Failed Service Bus operations by operation and error type.",
+	}, []string{"operation", "type"})
+	deadLetterMessages = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dlqt_dead_letter_messages",
+		Help: "Messages in the dead letter queue of each configured entity.",
+	}, []string{"namespace", "entity"})
+	activeMessages = promauto.With(metricsRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dlqt_active_messages",
+		Help: "Active messages in each configured entity.",
+	}, []string{"namespace", "entity"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// auth failure reasons
+const (
+	authFailureMissingToken = "missing_token"
+	authFailureInvalidToken = "invalid_token"
+	authFailureExpiredToken = "expired_token"
+	authFailureAudience     = "audience"
+	authFailureIssuer       = "issuer"
+	authFailureTenant       = "tenant"
+	authFailureUnknownRoute = "unknown_route"
+	authFailureForbidden    = "forbidden"
+)
+
+// operations instrumented around Service Bus calls
+const (
+	metricsOperationFetch         = "fetch"
+	metricsOperationPeek          = "peek"
+	metricsOperationRetrigger     = "retrigger"
+	metricsOperationBulkRetrigger = "bulk_retrigger"
+	metricsOperationStats         = "stats"
+	metricsOperationRuntimeCounts = "runtime_counts"
+	metricsOperationDetectSession = "detect_session"
+)
+
+// MetricsConfig selects the entities whose DLQ depth is exported as gauges
+type MetricsConfig struct {
+	// Entities are namespace/queue or namespace/topic/subscriptions/subscription, the Service Bus entity path
+	Entities []string `json:"entities"`
+	// Interval is how often their runtime counts are refreshed
+	Interval Duration `json:"interval"`
+}
+
+// depthEntity is a parsed MetricsConfig entity
+type depthEntity struct {
+	namespace string
+	entity    servicebus.Entity
+}
+
+// parseDepthEntity reads namespace/queue or namespace/topic/subscriptions/subscription
+func parseDepthEntity(value string) (depthEntity, error) {
+	namespace, path, ok := strings.Cut(value, "/")
+	if !ok {
+		return depthEntity{}, fmt.Errorf("entity '%s' must be namespace/queue or namespace/topic/subscriptions/subscription", value)
+	}
+	parsed := depthEntity{namespace: namespace, entity: servicebus.QueueEntity(path)}
+	if i := strings.Index(strings.ToLower(path), "/subscriptions/"); i >= 0 {
+		parsed.entity = servicebus.SubscriptionEntity(path[:i], path[i+len("/subscriptions/"):])
+	}
+	if err := servicebus.ValidateNamespaceName(parsed.namespace); err != nil {
+		return depthEntity{}, fmt.Errorf("entity '%s': %w", value, err)
+	}
+	if err := parsed.entity.ValidateNames(); err != nil {
+		return depthEntity{}, fmt.Errorf("entity '%s': %w", value, err)
+	}
+	return parsed, nil
+}
+
+// Validate checks every entity parses
+func (c *MetricsConfig) Validate() error {
+	var errs []error
+	for _, value := range c.Entities {
+		if _, err := parseDepthEntity(value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("interval must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// MetricsHandler serves the metrics in the Prometheus exposition format
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{Registry: metricsRegistry})
+}
+
+// metricsMethods are the request methods recorded as their own label value, any other method is "other" so
+// unauthenticated callers can't create label series
+var metricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// metricsMethod normalises a request method to a fixed set of label values
+func metricsMethod(method string) string {
+	if metricsMethods[method] {
+		return method
+	}
+	return "other"
+}
+
+// MetricsMiddleware counts and times every request to the route, including requests rejected by auth
+func MetricsMiddleware(route Route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"route": route.Path, "method": metricsMethod(r.Method), "status": strconv.Itoa(status)}
+		requestsTotal.With(labels).Inc()
+		requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeOperation records a Service Bus operation that started at start, counting its error by type
+func observeOperation(operation string, start time.Time, err error) {
+	operationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		operationsTotal.WithLabelValues(operation, "error").Inc()
+		serviceBusErrorsTotal.WithLabelValues(operation, serviceBusErrorType(err)).Inc()
+		return
+	}
+	operationsTotal.WithLabelValues(operation, "success").Inc()
+}
+
+// serviceBusErrorType classifies an error into a low-cardinality label: a Service Bus error code,
+// an HTTP status from the management API, or a context error
+func serviceBusErrorType(err error) string {
+	var sbErr *azservicebus.Error
+	var respErr *azcore.ResponseError
+	switch {
+	case errors.As(err, &sbErr):
+		return string(sbErr.Code)
+	case errors.As(err, &respErr):
+		return "http_" + strconv.Itoa(respErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "other"
+}
+
+// refreshDepth updates the DLQ depth gauges of the configured entities until ctx is done
+func refreshDepth(ctx context.Context, config *MetricsConfig) {
+	entities := make([]depthEntity, 0, len(config.Entities))
+	for _, value := range config.Entities {
+		// already validated with the config
+		parsed, _ := parseDepthEntity(value)
+		entities = append(entities, parsed)
+	}
+	interval := time.Duration(config.Interval)
+	if interval == 0 {
+		interval = defaultDepthInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, e := range entities {
+			updateDepth(ctx, e)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func updateDepth(ctx context.Context, e depthEntity) {
+	start := time.Now()
+	adminClient, err := clients.AdminClient(e.namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get admin client for DLQ depth", "namespace", e.namespace, "entity", e.entity.String(), "error", err)
+		return
+	}
+	counts, err := servicebus.GetRuntimeCounts(ctx, adminClient, e.entity)
+	observeOperation(metricsOperationRuntimeCounts, start, err)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to refresh DLQ depth", "namespace", e.namespace, "entity", e.entity.String(), "error", err)
+		return
+	}
+	labels := prometheus.Labels{"namespace": e.namespace, "entity": entityPath(e.entity)}
+	deadLetterMessages.With(labels).Set(float64(counts.DeadLetterMessageCount))
+	activeMessages.With(labels).Set(float64(counts.ActiveMessageCount))
+}
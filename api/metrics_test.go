@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestParseDepthEntity(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		namespace string
+		entity    servicebus.Entity
+		wantErr   bool
+	}{
+		{name: "Queue", value: "sb-dlqt/orders", namespace: "sb-dlqt", entity: servicebus.QueueEntity("orders")},
+		{name: "QueuePath", value: "sb-dlqt/orders/eu", namespace: "sb-dlqt", entity: servicebus.QueueEntity("orders/eu")},
+		{name: "Subscription", value: "sb-dlqt/events/Subscriptions/audit", namespace: "sb-dlqt", entity: servicebus.SubscriptionEntity("events", "audit")},
+		{name: "MissingEntity", value: "sb-dlqt", wantErr: true},
+		{name: "InvalidNamespace", value: "sb/orders", wantErr: true},
+		{name: "InvalidSubscription", value: "sb-dlqt/events/subscriptions/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseDepthEntity(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", parsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if parsed.namespace != tt.namespace || parsed.entity != tt.entity {
+				t.Errorf("expected %s %+v, got %s %+v", tt.namespace, tt.entity, parsed.namespace, parsed.entity)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	handler := MetricsMiddleware(Route{Path: "/metrics-test"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("RANDOM1", "/metrics-test", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("RANDOM2", "/metrics-test", nil))
+
+	observeOperation("metrics_test", time.Now(), fmt.Errorf("failed to peek: %w", &azservicebus.Error{Code: azservicebus.CodeNotFound}))
+	observeOperation("metrics_test", time.Now(), nil)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`dlqt_api_requests_total{method="GET",route="/metrics-test",status="403"} 1`,
+		`dlqt_api_request_duration_seconds_count{method="GET",route="/metrics-test",status="403"} 1`,
+		`dlqt_api_requests_total{method="other",route="/metrics-test",status="403"} 2`,
+		`dlqt_api_operations_total{operation="metrics_test",outcome="error"} 1`,
+		`dlqt_api_operations_total{operation="metrics_test",outcome="success"} 1`,
+		`dlqt_api_servicebus_errors_total{operation="metrics_test",type="notfound"} 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in metrics", want)
+		}
+	}
+	if strings.Contains(body, "RANDOM") {
+		t.Error("expected unknown methods to be recorded as other")
+	}
+}
@@ -1,105 +1,145 @@
-// TODO: use better logs for both internal and HTTP
-
 package main
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
 	"strings"
 
-	"github.com/MicahParks/keyfunc/v3"
+	"dlqt/internal/logging"
+
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
+var tracer = otel.Tracer("dlqt/api")
+
+// RequestIDHeader carries the request ID, a caller-provided value is kept when it looks like an ID
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware tags the request with an ID, echoed in the X-Request-ID response header and added to every
+// log line logged with the request context
+func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("AuthMiddleware: %s %s", r.Method, r.URL)
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func AuthMiddleware(config *AuthConfig, keys *JWKSCache, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		slog.DebugContext(ctx, "authenticating request", "method", r.Method, "path", r.URL.Path)
+
+		// the auth span covers token validation, including any JWKS refresh, but not the handler
+		_, span := tracer.Start(ctx, "auth")
+		reject := func(reason string, status int, message string) {
+			authFailuresTotal.WithLabelValues(reason).Inc()
+			span.SetAttributes(attribute.String("dlqt.auth.failure", reason))
+			span.SetStatus(codes.Error, reason)
+			span.End()
+			http.Error(w, message, status)
+		}
 
 		// extract token from Authorization header
 		authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			log.Printf("Missing or invalid Authorization header")
-			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+			slog.WarnContext(ctx, "missing or invalid Authorization header")
+			reject(authFailureMissingToken, http.StatusUnauthorized, "Missing or invalid Authorization header")
 			return
 		}
 		token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 		if token == "" {
-			log.Printf("Empty token")
-			http.Error(w, "Empty token", http.StatusUnauthorized)
-			return
-		}
-
-		// JWKS URL using CMD tenant ID
-		// TODO: remove hardcoded CMD tenant ID
-		jwksURL := "https://login.microsoftonline.com/f09f69e2-b684-4c08-9195-f8f10f54154c/discovery/v2.0/keys"
-		k, err := keyfunc.NewDefaultCtx(r.Context(), []string{jwksURL})
-		if err != nil {
-			log.Printf("Failed to create keyfunc: %v", err)
-			http.Error(w, "Failed to fetch JWKS", http.StatusInternalServerError)
+			slog.WarnContext(ctx, "empty token")
+			reject(authFailureMissingToken, http.StatusUnauthorized, "Empty token")
 			return
 		}
 
-		// parse token with keyfunc (signature verification and standard claims)
-		parsed, err := jwt.Parse(token, k.Keyfunc)
+		// parse token with the cached keys (signature verification and standard claims)
+		parsed, err := jwt.Parse(token, keys.Keyfunc().Keyfunc)
 		if err != nil {
-			log.Printf("token validation failed: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			slog.WarnContext(ctx, "token validation failed", "error", err)
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				reject(authFailureExpiredToken, http.StatusUnauthorized, "Invalid token")
+			} else {
+				reject(authFailureInvalidToken, http.StatusUnauthorized, "Invalid token")
+			}
 			return
 		}
 
 		// extract claims
 		claims, ok := parsed.Claims.(jwt.MapClaims)
 		if !ok {
-			log.Printf("failed to extract claims: %v", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			slog.WarnContext(ctx, "failed to extract claims")
+			reject(authFailureInvalidToken, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
 		// validate audience claim
-		// TODO: remove hardcoded API client ID
-		if claims["aud"] != "074c5ac1-4ab2-4a8a-b811-2d7b8c4e419f" {
-			log.Printf("invalid audience claim: %v", claims["aud"])
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		audiences, err := claims.GetAudience()
+		if err != nil || !slices.ContainsFunc(audiences, func(aud string) bool { return slices.Contains(config.Audiences, aud) }) {
+			slog.WarnContext(ctx, "invalid audience claim", "aud", claims["aud"])
+			reject(authFailureAudience, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
 		// validate issuer claim
-		// TODO: remove hardcoded CMD tenant ID
-		if claims["iss"] != "https://login.microsoftonline.com/f09f69e2-b684-4c08-9195-f8f10f54154c/v2.0" {
-			log.Printf("invalid issuer claim: %v", claims["iss"])
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		issuer, err := claims.GetIssuer()
+		if err != nil || !slices.Contains(config.Issuers, issuer) {
+			slog.WarnContext(ctx, "invalid issuer claim", "iss", claims["iss"])
+			reject(authFailureIssuer, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
-		// validate scope claim based on endpoint
-		scp, ok := claims["scp"].(string)
-		if !ok {
-			log.Printf("invalid scope claim: %v", claims["scp"])
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+		// validate tenant claim, when tenants are configured
+		if len(config.TenantIDs) > 0 {
+			tenantID, _ := claims["tid"].(string)
+			if !slices.Contains(config.TenantIDs, tenantID) {
+				slog.WarnContext(ctx, "invalid tenant claim", "tid", claims["tid"])
+				reject(authFailureTenant, http.StatusUnauthorized, "Invalid token")
+				return
+			}
 		}
 
-		var requiredScope string
-		switch r.URL.Path {
-		case "/fetch":
-			requiredScope = "dlq.read"
-		case "/retrigger":
-			requiredScope = "dlq.retrigger"
-		default:
-			log.Printf("unauthorized path: %s", r.URL.Path)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		// validate scope or app role claim against the route's permission
+		permission, ok := config.Permissions[r.URL.Path]
+		if !ok {
+			slog.WarnContext(ctx, "unauthorized path", "path", r.URL.Path)
+			reject(authFailureUnknownRoute, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
-		if !strings.Contains(scp, requiredScope) {
-			log.Printf("missing required scope %s in claim: %v", requiredScope, claims["scp"])
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		principal := NewPrincipal(claims)
+		if !permission.Allows(principal) {
+			slog.WarnContext(ctx, "missing required scope or role", "principal", principal.String(), "scope", permission.Scope, "role", permission.Role, "scp", claims["scp"], "roles", claims["roles"])
+			reject(authFailureForbidden, http.StatusForbidden, "Forbidden")
 			return
 		}
 
-		log.Println("token validated successfully")
+		slog.DebugContext(ctx, "token validated", "principal", principal.String())
+		span.End()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", principal.ObjectID))
 
 		// proceed to handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, principal)))
 	})
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dlqt/internal/logging"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testAudience = "api-client-id"
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jwks := newTestJWKS(t)
+	jwks.addKey("key-1")
+	config := &AuthConfig{
+		TenantIDs: []string{testTenantID},
+		Audiences: []string{testAudience},
+		JWKSURLs:  []string{jwks.server.URL},
+	}
+	config.applyDefaults()
+	keys := jwks.cache(ctx, time.Hour, time.Hour)
+
+	handler := AuthMiddleware(config, keys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"aud": testAudience,
+			"iss": "https://login.microsoftonline.com/" + testTenantID + "/v2.0",
+			"tid": testTenantID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		for key, value := range extra {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{name: "Scope", path: "/fetch", claims: claims(jwt.MapClaims{"scp": "dlq.read"}), want: http.StatusNoContent},
+		{name: "OneOfScopes", path: "/retrigger", claims: claims(jwt.MapClaims{"scp": "dlq.read dlq.retrigger"}), want: http.StatusNoContent},
+		{name: "ScopePrefix", path: "/fetch", claims: claims(jwt.MapClaims{"scp": "dlq.readonly"}), want: http.StatusForbidden},
+		{name: "WrongScope", path: "/retrigger", claims: claims(jwt.MapClaims{"scp": "dlq.read"}), want: http.StatusForbidden},
+		{name: "AppRole", path: "/stats", claims: claims(jwt.MapClaims{"roles": []any{"dlq.read"}}), want: http.StatusNoContent},
+		{name: "AppRolePrefix", path: "/stats", claims: claims(jwt.MapClaims{"roles": []any{"dlq.reader"}}), want: http.StatusForbidden},
+		{name: "NoScopeOrRole", path: "/fetch", claims: claims(nil), want: http.StatusForbidden},
+		{name: "UnknownRoute", path: "/admin", claims: claims(jwt.MapClaims{"scp": "dlq.read"}), want: http.StatusUnauthorized},
+		{name: "WrongAudience", path: "/fetch", claims: claims(jwt.MapClaims{"scp": "dlq.read", "aud": "other"}), want: http.StatusUnauthorized},
+		{name: "WrongTenant", path: "/fetch", claims: claims(jwt.MapClaims{"scp": "dlq.read", "tid": "other"}), want: http.StatusUnauthorized},
+		{name: "Expired", path: "/fetch", claims: claims(jwt.MapClaims{"scp": "dlq.read", "exp": time.Now().Add(-time.Hour).Unix()}), want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+jwks.sign("key-1", tt.claims))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("MissingToken", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fetch", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"Generated", "", false},
+		{"Incoming", "client-req.42", true},
+		{"InvalidIncoming", "bad id\n", false},
+		{"TooLong", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/fetch", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			header := rec.Header().Get(RequestIDHeader)
+			if header == "" || header != got {
+				t.Fatalf("expected response header %q to match the context request ID %q", header, got)
+			}
+			if (header == tt.incoming) != tt.keep {
+				t.Errorf("expected incoming ID kept %v, got %q", tt.keep, header)
+			}
+		})
+	}
+}
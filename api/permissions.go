@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Permission is what a route requires: a delegated scope in the scp claim for users,
+// or an app role in the roles claim for service principals. An empty field denies that kind of caller.
+type Permission struct {
+	Scope string `json:"scope,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// Route is an API endpoint, the permission it requires unless the config overrides it, and its policy operation
+type Route struct {
+	Path       string
+	Handler    http.HandlerFunc
+	Permission Permission
+	Operation  string
+}
+
+// routes is the table of authorized API endpoints, a new endpoint only needs an entry here
+var routes = []Route{
+	{Path: "/fetch", Handler: fetchHandler, Permission: Permission{Scope: "dlq.read", Role: "dlq.read"}, Operation: OperationRead},
+	{Path: "/messages", Handler: messagesHandler, Permission: Permission{Scope: "dlq.read", Role: "dlq.read"}, Operation: OperationRead},
+	{Path: "/stats", Handler: statsHandler, Permission: Permission{Scope: "dlq.read", Role: "dlq.read"}, Operation: OperationRead},
+	{Path: "/retrigger", Handler: retriggerHandler, Permission: Permission{Scope: "dlq.retrigger", Role: "dlq.retrigger"}, Operation: OperationRetrigger},
+}
+
+// defaultPermission returns the permission a route requires by default
+func defaultPermission(path string) (Permission, bool) {
+	for _, route := range routes {
+		if route.Path == path {
+			return route.Permission, true
+		}
+	}
+	return Permission{}, false
+}
+
+// Allows reports whether the caller's scopes or app roles grant the permission, matching whole values exactly
+func (p Permission) Allows(principal *Principal) bool {
+	if p.Scope != "" && slices.Contains(principal.Scopes, p.Scope) {
+		return true
+	}
+	return p.Role != "" && slices.Contains(principal.Roles, p.Role)
+}
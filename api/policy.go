@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"dlqt/internal/servicebus"
+)
+
+// operations a policy rule can allow, routes declare theirs in the route table.
+// No route requires purge or export yet, rules may grant them so they apply once those routes exist.
+const (
+	OperationRead      = "read"
+	OperationRetrigger = "retrigger"
+	OperationPurge     = "purge"
+	OperationExport    = "export"
+)
+
+var operations = []string{OperationRead, OperationRetrigger, OperationPurge, OperationExport}
+
+// Policy is a set of allow rules, a request is allowed when any rule matches the caller, namespace, entity and operation
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule allows callers in any of its groups, app roles or users to perform its operations on matching entities.
+// Namespaces and queues are glob patterns, a subscription is matched by its Service Bus path topic/Subscriptions/subscription,
+// which no queue name can take, so * matches queues and */Subscriptions/* subscriptions. * in users matches any caller.
+type PolicyRule struct {
+	Name       string   `json:"name"`
+	Groups     []string `json:"groups,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+	Users      []string `json:"users,omitempty"`
+	Namespaces []string `json:"namespaces"`
+	Queues     []string `json:"queues"`
+	Operations []string `json:"operations"`
+}
+
+// LoadPolicy reads a policy file, an empty file name returns a nil policy that allows everything
+func LoadPolicy(file string) (*Policy, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	defer f.Close()
+
+	var policy Policy
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file '%s': %w", file, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file '%s': %w", file, err)
+	}
+	return &policy, nil
+}
+
+// Validate reports every problem with the policy at once
+func (p *Policy) Validate() error {
+	var errs []error
+	for i, rule := range p.Rules {
+		name := rule.label(i)
+		if len(rule.Groups) == 0 && len(rule.Roles) == 0 && len(rule.Users) == 0 {
+			errs = append(errs, fmt.Errorf("rule %s needs groups, roles or users", name))
+		}
+		if len(rule.Namespaces) == 0 || len(rule.Queues) == 0 {
+			errs = append(errs, fmt.Errorf("rule %s needs namespaces and queues, use * for all namespaces and queues and */Subscriptions/* for all subscriptions", name))
+		}
+		for _, pattern := range slices.Concat(rule.Namespaces, rule.Queues) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s pattern '%s': %w", name, pattern, err))
+			}
+		}
+		if len(rule.Operations) == 0 {
+			errs = append(errs, fmt.Errorf("rule %s needs operations", name))
+		}
+		for _, operation := range rule.Operations {
+			if operation != "*" && !slices.Contains(operations, operation) {
+				errs = append(errs, fmt.Errorf("rule %s operation '%s' must be * or one of %s", name, operation, strings.Join(operations, ", ")))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate returns nil when a rule allows the request, otherwise an error giving the reason it's denied
+func (p *Policy) Evaluate(principal *Principal, namespace string, entity servicebus.Entity, operation string) error {
+	if p == nil {
+		return nil
+	}
+
+	entityName := entityPath(entity)
+
+	// deny with the reason of the rule that got furthest, checked in order: caller, namespace, entity, operation
+	var applicable, namespaceRules, entityRules []string
+	for i, rule := range p.Rules {
+		if !rule.matchesCaller(principal) {
+			continue
+		}
+		applicable = append(applicable, rule.label(i))
+		if !matchesAny(rule.Namespaces, namespace) {
+			continue
+		}
+		namespaceRules = append(namespaceRules, rule.label(i))
+		if !matchesAny(rule.Queues, entityName) {
+			continue
+		}
+		entityRules = append(entityRules, rule.label(i))
+		if slices.Contains(rule.Operations, "*") || slices.Contains(rule.Operations, operation) {
+			return nil
+		}
+	}
+
+	switch {
+	case len(applicable) == 0 && principal.GroupOverage:
+		return errors.New("no policy rule applies to the caller, the token has too many groups to include them, use app roles or users instead")
+	case len(applicable) == 0:
+		return errors.New("no policy rule applies to the caller")
+	case len(namespaceRules) == 0:
+		return fmt.Errorf("namespace '%s' is not allowed by rules %s", namespace, strings.Join(applicable, ", "))
+	case len(entityRules) == 0:
+		return fmt.Errorf("%s is not allowed in namespace '%s' by rules %s", entity, namespace, strings.Join(namespaceRules, ", "))
+	default:
+		return fmt.Errorf("operation '%s' on %s is not allowed by rules %s", operation, entity, strings.Join(entityRules, ", "))
+	}
+}
+
+// label names a rule in errors, unnamed rules by their position
+func (rule *PolicyRule) label(i int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+func (rule *PolicyRule) matchesCaller(principal *Principal) bool {
+	if slices.Contains(rule.Users, "*") || (principal.ObjectID != "" && slices.Contains(rule.Users, principal.ObjectID)) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if slices.Contains(rule.Roles, role) {
+			return true
+		}
+	}
+	for _, group := range principal.Groups {
+		if slices.Contains(rule.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// entityPath names the entity for glob patterns, a subscription as topic/Subscriptions/subscription like Service Bus,
+// queue names can contain slashes but not a subscriptions segment so the paths never collide
+func entityPath(entity servicebus.Entity) string {
+	if entity.IsSubscription() {
+		return entity.Topic + "/Subscriptions/" + entity.Subscription
+	}
+	return entity.Queue
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyMiddleware evaluates the policy for the route's operation before the handler runs
+func PolicyMiddleware(policy *Policy, operation string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.URL.Query().Get("namespace")
+		entity, err := entityFromQuery(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := policy.Evaluate(PrincipalFromContext(r.Context()), namespace, entity, operation); err != nil {
+			slog.WarnContext(r.Context(), "policy denied request", "principal", PrincipalFromContext(r.Context()).String(), "operation", operation, "namespace", namespace, "entity", entity.String(), "error", err)
+			respondErrorMessage(w, http.StatusForbidden, "access denied by policy", err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testPolicy = &Policy{Rules: []PolicyRule{
+	{
+		Name:       "payments",
+		Groups:     []string{"payments-group"},
+		Namespaces: []string{"sb-payments-*"},
+		Queues:     []string{"orders", "invoices/Subscriptions/*"},
+		Operations: []string{OperationRead},
+	},
+	{
+		Name:       "auditor",
+		Users:      []string{"auditor-oid"},
+		Namespaces: []string{"*"},
+		Queues:     []string{"*", "*/Subscriptions/*"},
+		Operations: []string{OperationRead},
+	},
+	{
+		Name:       "replayer",
+		Roles:      []string{"dlq.retrigger"},
+		Namespaces: []string{"sb-payments-prod"},
+		Queues:     []string{"orders"},
+		Operations: []string{"*"},
+	},
+}}
+
+func TestPolicyEvaluate(t *testing.T) {
+	payments := &Principal{ObjectID: "user-oid", Groups: []string{"payments-group"}}
+	auditor := &Principal{ObjectID: "auditor-oid"}
+	replayer := &Principal{ObjectID: "app-oid", Roles: []string{"dlq.retrigger"}}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		namespace  string
+		entity     servicebus.Entity
+		operation  string
+		wantReason string
+	}{
+		{name: "GroupAllowed", principal: payments, namespace: "sb-payments-dev", entity: servicebus.QueueEntity("orders"), operation: OperationRead},
+		{name: "GroupSubscription", principal: payments, namespace: "sb-payments-dev", entity: servicebus.SubscriptionEntity("invoices", "audit"), operation: OperationRead},
+		{name: "GroupNamespace", principal: payments, namespace: "sb-shipping", entity: servicebus.QueueEntity("orders"), operation: OperationRead, wantReason: "namespace 'sb-shipping' is not allowed by rules payments"},
+		{name: "GroupQueueWithSlash", principal: payments, namespace: "sb-payments-dev", entity: servicebus.QueueEntity("invoices/audit"), operation: OperationRead, wantReason: "queue 'invoices/audit' is not allowed"},
+		{name: "GroupQueue", principal: payments, namespace: "sb-payments-dev", entity: servicebus.QueueEntity("refunds"), operation: OperationRead, wantReason: "queue 'refunds' is not allowed"},
+		{name: "GroupOperation", principal: payments, namespace: "sb-payments-dev", entity: servicebus.QueueEntity("orders"), operation: OperationRetrigger, wantReason: "operation 'retrigger'"},
+		{name: "UserRead", principal: auditor, namespace: "sb-anything", entity: servicebus.SubscriptionEntity("t", "s"), operation: OperationRead},
+		{name: "UserRetrigger", principal: auditor, namespace: "sb-anything", entity: servicebus.QueueEntity("orders"), operation: OperationRetrigger, wantReason: "operation 'retrigger'"},
+		{name: "RoleWildcardOperation", principal: replayer, namespace: "sb-payments-prod", entity: servicebus.QueueEntity("orders"), operation: OperationRetrigger},
+		{name: "NoRule", principal: &Principal{ObjectID: "stranger"}, namespace: "sb-payments-dev", entity: servicebus.QueueEntity("orders"), operation: OperationRead, wantReason: "no policy rule applies"},
+		{name: "GroupOverage", principal: NewPrincipal(jwt.MapClaims{"oid": "stranger", "_claim_names": map[string]any{"groups": "src1"}}), namespace: "sb-payments-dev", entity: servicebus.QueueEntity("orders"), operation: OperationRead, wantReason: "too many groups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testPolicy.Evaluate(tt.principal, tt.namespace, tt.entity, tt.operation)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("expected allowed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("expected denied with %q, got %v", tt.wantReason, err)
+			}
+		})
+	}
+
+	t.Run("NilPolicy", func(t *testing.T) {
+		var policy *Policy
+		if err := policy.Evaluate(nil, "sb", servicebus.QueueEntity("q"), OperationRetrigger); err != nil {
+			t.Errorf("expected nil policy to allow, got %v", err)
+		}
+	})
+}
+
+func TestLoadPolicy(t *testing.T) {
+	write := func(t *testing.T, data string) string {
+		file := filepath.Join(t.TempDir(), "policy.json")
+		if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write policy file: %v", err)
+		}
+		return file
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		policy, err := LoadPolicy(write(t, `{"rules": [{"name": "all", "users": ["*"], "namespaces": ["*"], "queues": ["*"], "operations": ["read", "purge", "export"]}]}`))
+		if err != nil {
+			t.Fatalf("failed to load policy: %v", err)
+		}
+		if len(policy.Rules) != 1 {
+			t.Errorf("expected 1 rule, got %d", len(policy.Rules))
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := LoadPolicy(write(t, `{"rules": [{"namespaces": ["["], "operations": ["delete"]}]}`))
+		if err == nil {
+			t.Fatal("expected invalid policy error")
+		}
+		for _, want := range []string{"needs groups, roles or users", "needs namespaces and queues", "syntax error in pattern", "operation 'delete'"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in %v", want, err)
+			}
+		}
+	})
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	handler := PolicyMiddleware(testPolicy, OperationRetrigger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/retrigger?namespace=sb-shipping&queue=orders", nil)
+	req = req.WithContext(WithPrincipal(req.Context(), &Principal{Groups: []string{"payments-group"}}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "namespace 'sb-shipping' is not allowed") {
+		t.Errorf("expected deny reason in body, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Principal is the authenticated caller of a request, read from its validated access token
+type Principal struct {
+	// ObjectID is the oid claim, the caller's user or service principal object ID
+	ObjectID string
+	// UPN is the user's sign-in name, empty for service principals
+	UPN      string
+	Name     string
+	TenantID string
+	// AppID is the client app the token was issued to
+	AppID string
+	// Scopes are the delegated scopes of a user token, Roles the app roles of a service principal or user
+	Scopes []string
+	Roles  []string
+	Groups []string
+	// GroupOverage is set when Entra ID left the groups out of the token because the caller is in too many
+	GroupOverage bool
+}
+
+// NewPrincipal reads the caller from validated token claims, v1 and v2 token claim names are both understood
+func NewPrincipal(claims jwt.MapClaims) *Principal {
+	names, _ := claims["_claim_names"].(map[string]any)
+	_, overage := names["groups"]
+	scp, _ := claims["scp"].(string)
+	return &Principal{
+		ObjectID:     claimString(claims, "oid"),
+		UPN:          firstClaimString(claims, "upn", "preferred_username"),
+		Name:         claimString(claims, "name"),
+		TenantID:     claimString(claims, "tid"),
+		AppID:        firstClaimString(claims, "azp", "appid"),
+		Scopes:       strings.Fields(scp),
+		Roles:        claimStrings(claims, "roles"),
+		Groups:       claimStrings(claims, "groups"),
+		GroupOverage: overage,
+	}
+}
+
+// String identifies the caller in logs and the dlqt-retriggered-by property: a user's UPN, otherwise the app ID or object ID
+func (p *Principal) String() string {
+	switch {
+	case p == nil:
+		return ""
+	case p.UPN != "":
+		return p.UPN
+	case p.AppID != "":
+		return p.AppID
+	}
+	return p.ObjectID
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal stores the authenticated caller for later middleware and handlers
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller, nil when the request wasn't authenticated
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// claimStrings reads a claim that is an array of strings
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	values, _ := claims[name].([]any)
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// firstClaimString returns the first of the claims that is set, for claims named differently in v1 and v2 tokens
+func firstClaimString(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if value := claimString(claims, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNewPrincipal(t *testing.T) {
+	t.Run("UserV2", func(t *testing.T) {
+		principal := NewPrincipal(jwt.MapClaims{
+			"oid":                "user-oid",
+			"preferred_username": "user@contoso.com",
+			"name":               "Some User",
+			"tid":                testTenantID,
+			"azp":                "cli-app-id",
+			"scp":                "dlq.read dlq.retrigger",
+			"groups":             []any{"group-1", "group-2"},
+		})
+		if principal.ObjectID != "user-oid" || principal.UPN != "user@contoso.com" || principal.Name != "Some User" || principal.TenantID != testTenantID || principal.AppID != "cli-app-id" {
+			t.Errorf("unexpected principal %+v", principal)
+		}
+		if !slices.Equal(principal.Scopes, []string{"dlq.read", "dlq.retrigger"}) || !slices.Equal(principal.Groups, []string{"group-1", "group-2"}) {
+			t.Errorf("expected scopes and groups from claims, got %v %v", principal.Scopes, principal.Groups)
+		}
+		if principal.String() != "user@contoso.com" {
+			t.Errorf("expected UPN to identify a user, got %q", principal.String())
+		}
+	})
+
+	t.Run("ServicePrincipalV1", func(t *testing.T) {
+		principal := NewPrincipal(jwt.MapClaims{
+			"oid":          "app-oid",
+			"appid":        "app-client-id",
+			"roles":        []any{"dlq.retrigger"},
+			"_claim_names": map[string]any{"groups": "src1"},
+		})
+		if principal.AppID != "app-client-id" || !slices.Equal(principal.Roles, []string{"dlq.retrigger"}) || !principal.GroupOverage {
+			t.Errorf("unexpected principal %+v", principal)
+		}
+		if principal.String() != "app-client-id" {
+			t.Errorf("expected app ID to identify a service principal, got %q", principal.String())
+		}
+	})
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"dlqt/internal/decode"
 	"dlqt/internal/servicebus"
 )
 
@@ -14,14 +20,43 @@ type ErrorResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+type RetriggerRequest struct {
+	MessageID      string           `json:"message-id,omitempty"`
+	SequenceNumber *int64           `json:"sequence-number,omitempty"`
+	All            bool             `json:"all,omitempty"`
+	Filter         *RetriggerFilter `json:"filter,omitempty"`
+	Limit          int              `json:"limit,omitempty"`
+	DryRun         bool             `json:"dry-run,omitempty"`
+	// TargetSubscription marks a message re-published to a topic as meant only for the source subscription
+	TargetSubscription bool `json:"target-subscription,omitempty"`
+	// Annotations are extra application properties set on the retriggered messages, without the dlqt- prefix
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// DisableAnnotations skips the dlqt-* properties recording when, by whom and how often a message was retriggered
+	DisableAnnotations bool `json:"disable-annotations,omitempty"`
+}
+
+type RetriggerFilter struct {
+	Reason                string            `json:"reason,omitempty"`
+	ErrorDescription      string            `json:"error-description,omitempty"`
+	Since                 *time.Time        `json:"since,omitempty"`
+	Until                 *time.Time        `json:"until,omitempty"`
+	Subject               string            `json:"subject,omitempty"`
+	ApplicationProperties map[string]string `json:"application-properties,omitempty"`
+}
+
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
 func respondError(w http.ResponseWriter, status int, message string) {
+	respondErrorMessage(w, status, message, "")
+}
+
+// respondErrorMessage responds with an error and a message explaining it
+func respondErrorMessage(w http.ResponseWriter, status int, summary string, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(ErrorResponse{Error: summary, Message: message})
 }
 
 func respondSuccess(w http.ResponseWriter, message string) {
@@ -30,6 +65,55 @@ func respondSuccess(w http.ResponseWriter, message string) {
 	json.NewEncoder(w).Encode(SuccessResponse{Message: message})
 }
 
+const (
+	defaultPeekLimit = 50
+	maxPeekLimit     = 250
+
+	defaultStatsScanLimit = 10000
+	maxStatsScanLimit     = 100000
+
+	// maxRetriggerBodySize bounds retrigger request bodies, whose filters and annotations are otherwise unbounded
+	maxRetriggerBodySize = 1 << 20
+)
+
+// entityFromQuery reads either a queue, or a topic and subscription, from the query parameters
+func entityFromQuery(r *http.Request) (servicebus.Entity, error) {
+	entity := servicebus.Entity{
+		Queue:        r.URL.Query().Get("queue"),
+		Topic:        r.URL.Query().Get("topic"),
+		Subscription: r.URL.Query().Get("subscription"),
+	}
+	return entity, entity.Validate()
+}
+
+// bodyOptionsFromQuery reads how message bodies are rendered, JSON bodies are pretty-printed unless pretty=false,
+// and bodies are decoded only when a decoder is requested
+func bodyOptionsFromQuery(r *http.Request) (*servicebus.BodyOptions, error) {
+	options := &servicebus.BodyOptions{Pretty: true, Redactor: redactor}
+	if v := r.URL.Query().Get("pretty"); v != "" {
+		pretty, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("pretty must be true or false")
+		}
+		options.Pretty = pretty
+	}
+	if v := r.URL.Query().Get("max-body-size"); v != "" {
+		maxSize, err := strconv.Atoi(v)
+		if err != nil || maxSize < 0 {
+			return nil, fmt.Errorf("max-body-size must be a non-negative number of bytes")
+		}
+		options.MaxSize = maxSize
+	}
+	if v := r.URL.Query().Get("decoder"); v != "" && v != decode.None {
+		if _, ok := decoders.Lookup(v); !ok && v != decode.Auto {
+			return nil, fmt.Errorf("decoder must be %s, %s or one of %s", decode.Auto, decode.None, strings.Join(decoders.Names(), ", "))
+		}
+		options.Decoders = decoders
+		options.Decoder = v
+	}
+	return options, nil
+}
+
 func fetchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -38,64 +122,51 @@ func fetchHandler(w http.ResponseWriter, r *http.Request) {
 
 	// extract query parameters
 	namespace := r.URL.Query().Get("namespace")
-	queue := r.URL.Query().Get("queue")
-	slog.Info("received fetch request", "namespace", namespace, "queue", queue)
+	entity, err := entityFromQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bodyOptions, err := bodyOptionsFromQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slog.InfoContext(r.Context(), "received fetch request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String())
 
-	// create service bus client
-	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
+	// get the namespace's shared service bus client
+	client, release, err := clients.Client(namespace + ".servicebus.windows.net")
 	if err != nil {
-		slog.Error("failed to get service bus client", "error", err)
+		slog.ErrorContext(r.Context(), "failed to get service bus client", "error", err)
 		respondError(w, http.StatusInternalServerError, "failed to get service bus client")
 		return
 	}
+	defer release()
 
 	// fetch dead letter message
-	message, err := servicebus.FetchDeadLetterMessage(r.Context(), client, queue)
+	start := time.Now()
+	message, err := servicebus.FetchDeadLetterMessage(r.Context(), client, entity)
+	observeOperation(metricsOperationFetch, start, err)
 	if err != nil {
-		slog.Error("failed to fetch dead letter message", "error", err)
+		slog.ErrorContext(r.Context(), "failed to fetch dead letter message", "error", err)
 		respondError(w, http.StatusInternalServerError, "failed to fetch dead letter message")
 		return
 	}
 
-	// get string of the first slice of bytes
-	var messageBody string
-	if len(message.Body) > 0 {
-		messageBody = string(message.Body[0])
+	if message == nil {
+		respondError(w, http.StatusNotFound, "no messages in dead letter queue")
+		return
 	}
 
+	auditEvent(r.Context()).Messages = []AuditMessage{{MessageID: message.MessageID, SequenceNumber: message.SequenceNumber}}
+
 	// map to JSON-serializable struct
-	deadLetterMessage := &servicebus.DeadLetterMessage{
-		Namespace:                  namespace,
-		Queue:                      queue,
-		MessageID:                  message.MessageID,
-		Body:                       messageBody,
-		ContentType:                message.ContentType,
-		CorrelationID:              message.CorrelationID,
-		DeadLetterErrorDescription: message.DeadLetterErrorDescription,
-		DeadLetterReason:           message.DeadLetterReason,
-		DeadLetterSource:           message.DeadLetterSource,
-		DeliveryCount:              message.DeliveryCount,
-		EnqueuedSequenceNumber:     message.EnqueuedSequenceNumber,
-		EnqueuedTime:               message.EnqueuedTime,
-		ExpiresAt:                  message.ExpiresAt,
-		LockedUntil:                message.LockedUntil,
-		PartitionKey:               message.PartitionKey,
-		ReplyTo:                    message.ReplyTo,
-		ReplyToSessionID:           message.ReplyToSessionID,
-		ScheduledEnqueueTime:       message.ScheduledEnqueueTime,
-		SequenceNumber:             message.SequenceNumber,
-		SessionID:                  message.SessionID,
-		State:                      int32(message.State),
-		Subject:                    message.Subject,
-		TimeToLive:                 message.TimeToLive,
-		To:                         message.To,
-		ApplicationProperties:      message.ApplicationProperties,
-	}
+	deadLetterMessage := wireMessage(servicebus.NewDeadLetterMessage(namespace, entity, message, bodyOptions))
 
 	// convert to JSON
 	jsonResponse, err := json.Marshal(deadLetterMessage)
 	if err != nil {
-		slog.Error("failed to marshal dead letter message to JSON", "error", err)
+		slog.ErrorContext(r.Context(), "failed to marshal dead letter message to JSON", "error", err)
 		respondError(w, http.StatusInternalServerError, "failed to marshal dead letter message")
 		return
 	}
@@ -113,41 +184,357 @@ func retriggerHandler(w http.ResponseWriter, r *http.Request) {
 
 	// extract query parameters
 	namespace := r.URL.Query().Get("namespace")
-	queue := r.URL.Query().Get("queue")
-
-	// extract messageID from body
-	var requestBody map[string]string
-	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	entity, err := entityFromQuery(r)
 	if err != nil {
-		slog.Error("failed to decode JSON", "error", err)
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// extract message ID or sequence number from body
+	var requestBody RetriggerRequest
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRetriggerBodySize)).Decode(&requestBody)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit))
+		return
+	} else if err != nil {
+		slog.ErrorContext(r.Context(), "failed to decode JSON", "error", err)
 		respondError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 
-	// extract messageID from body
-	messageID, ok := requestBody["message-id"]
-	if !ok {
-		slog.Error("message-id not provided in request body")
-		respondError(w, http.StatusBadRequest, "message-id not provided")
+	modes := 0
+	for _, set := range []bool{requestBody.MessageID != "", requestBody.SequenceNumber != nil, requestBody.All} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		slog.ErrorContext(r.Context(), "exactly one of message-id, sequence-number or all must be provided in request body")
+		respondError(w, http.StatusBadRequest, "exactly one of message-id, sequence-number or all must be provided")
+		return
+	}
+	if !requestBody.All && (requestBody.Filter != nil || requestBody.Limit != 0 || requestBody.DryRun) {
+		respondError(w, http.StatusBadRequest, "filter, limit and dry-run require all")
+		return
+	}
+	if requestBody.Limit < 0 {
+		respondError(w, http.StatusBadRequest, "limit must not be negative")
+		return
+	}
+
+	for name := range requestBody.Annotations {
+		if name == "" || strings.HasPrefix(strings.ToLower(name), servicebus.AnnotationPrefix) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("annotation names must be non-empty and not start with %s", servicebus.AnnotationPrefix))
+			return
+		}
+	}
+
+	if requestBody.TargetSubscription && !entity.IsSubscription() {
+		respondError(w, http.StatusBadRequest, "target-subscription requires a topic and subscription")
+		return
+	}
+
+	// detect session-enabled entities so messages without a session ID are rejected before settling
+	entity = detectSession(r.Context(), namespace, entity)
+
+	if requestBody.All {
+		bulkRetrigger(w, r, namespace, entity, &requestBody)
 		return
 	}
 
-	slog.Info("received retrigger request", "namespace", namespace, "queue", queue, "messageID", messageID)
+	if requestBody.SequenceNumber != nil {
+		slog.InfoContext(r.Context(), "received retrigger request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String(), "sequenceNumber", *requestBody.SequenceNumber)
+	} else {
+		slog.InfoContext(r.Context(), "received retrigger request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String(), "messageID", requestBody.MessageID)
+	}
 
-	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
+	client, release, err := clients.Client(namespace + ".servicebus.windows.net")
 	if err != nil {
-		slog.Error("failed to get service bus client", "error", err)
+		slog.ErrorContext(r.Context(), "failed to get service bus client", "error", err)
 		respondError(w, http.StatusInternalServerError, "failed to get service bus client")
 		return
 	}
+	defer release()
 
-	err = servicebus.RetriggerDeadLetterMessage(r.Context(), client, queue, messageID)
+	auditEvent(r.Context()).Messages = []AuditMessage{{MessageID: requestBody.MessageID, SequenceNumber: requestBody.SequenceNumber}}
+
+	var retriggered string
+	start := time.Now()
+	if requestBody.SequenceNumber != nil {
+		retriggered = fmt.Sprintf("message with sequence number %d", *requestBody.SequenceNumber)
+		err = servicebus.RetriggerDeadLetterMessageBySequenceNumber(r.Context(), client, entity, *requestBody.SequenceNumber, retriggerOptions(r, entity, &requestBody))
+	} else {
+		retriggered = fmt.Sprintf("message %s", requestBody.MessageID)
+		err = servicebus.RetriggerDeadLetterMessage(r.Context(), client, entity, requestBody.MessageID, retriggerOptions(r, entity, &requestBody))
+	}
+	observeOperation(metricsOperationRetrigger, start, err)
 	if err != nil {
-		slog.Error("failed to retrigger dead letter message", "error", err)
+		slog.ErrorContext(r.Context(), "failed to retrigger dead letter message", "error", err)
 		respondError(w, http.StatusInternalServerError, "failed to retrigger message")
 		return
 	}
 
 	// Send success response
-	respondSuccess(w, fmt.Sprintf("message %s retriggered successfully", messageID))
+	respondSuccess(w, fmt.Sprintf("%s retriggered successfully", retriggered))
+}
+
+// detectSession looks up whether the entity requires sessions, falling back to the entity as given
+func detectSession(ctx context.Context, namespace string, entity servicebus.Entity) servicebus.Entity {
+	adminClient, err := clients.AdminClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get service bus admin client", "error", err)
+		return entity
+	}
+
+	start := time.Now()
+	detected, err := servicebus.DetectSession(ctx, adminClient, entity)
+	observeOperation(metricsOperationDetectSession, start, err)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to detect session-enabled entity", "entity", entity.String(), "error", err)
+		return entity
+	}
+	return detected
+}
+
+// retriggerOptions builds the options for messages resent by a retrigger request, stamped with the caller
+func retriggerOptions(r *http.Request, entity servicebus.Entity, requestBody *RetriggerRequest) *servicebus.RetriggerOptions {
+	options := &servicebus.RetriggerOptions{
+		RetriggeredBy:      PrincipalFromContext(r.Context()).String(),
+		DisableAnnotations: requestBody.DisableAnnotations,
+	}
+	if requestBody.TargetSubscription {
+		options.TargetSubscription = entity.Subscription
+	}
+	if len(requestBody.Annotations) > 0 {
+		options.Annotations = make(map[string]any, len(requestBody.Annotations))
+		for name, value := range requestBody.Annotations {
+			options.Annotations[name] = value
+		}
+	}
+	return options
+}
+
+func bulkRetrigger(w http.ResponseWriter, r *http.Request, namespace string, entity servicebus.Entity, requestBody *RetriggerRequest) {
+	slog.InfoContext(r.Context(), "received bulk retrigger request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String(), "limit", requestBody.Limit, "dryRun", requestBody.DryRun)
+
+	client, release, err := clients.Client(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get service bus client", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get service bus client")
+		return
+	}
+	defer release()
+
+	options := &servicebus.BulkRetriggerOptions{
+		Limit:     requestBody.Limit,
+		DryRun:    requestBody.DryRun,
+		Retrigger: retriggerOptions(r, entity, requestBody),
+	}
+	if f := requestBody.Filter; f != nil {
+		options.Filter = &servicebus.MessageFilter{
+			DeadLetterReason:           f.Reason,
+			DeadLetterErrorDescription: f.ErrorDescription,
+			EnqueuedAfter:              f.Since,
+			EnqueuedBefore:             f.Until,
+			Subject:                    f.Subject,
+			ApplicationProperties:      f.ApplicationProperties,
+		}
+	}
+
+	start := time.Now()
+	result, err := servicebus.BulkRetriggerDeadLetterMessages(r.Context(), client, entity, options)
+	observeOperation(metricsOperationBulkRetrigger, start, err)
+	event := auditEvent(r.Context())
+	event.DryRun = requestBody.DryRun
+	if result != nil {
+		for _, matched := range result.Matched {
+			event.Messages = append(event.Messages, AuditMessage{MessageID: matched.MessageID, SequenceNumber: &matched.SequenceNumber})
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to bulk retrigger dead letter messages", "error", err)
+		message := "failed to retrigger messages"
+		if result != nil {
+			message = fmt.Sprintf("failed to retrigger messages after retriggering %d of %d", result.Retriggered, len(result.Matched))
+		}
+		respondError(w, http.StatusInternalServerError, message)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "bulk retrigger completed", "namespace", namespace, "entity", entity.String(), "matched", len(result.Matched), "retriggered", result.Retriggered)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wireBulkRetriggerResult(result))
+}
+
+func messagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// extract query parameters
+	namespace := r.URL.Query().Get("namespace")
+	entity, err := entityFromQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var from int64
+	if v := r.URL.Query().Get("from"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			respondError(w, http.StatusBadRequest, "from must be a non-negative sequence number")
+			return
+		}
+		from = parsed
+	}
+
+	limit := defaultPeekLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxPeekLimit {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPeekLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	bodyOptions, err := bodyOptionsFromQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	slog.InfoContext(r.Context(), "received messages request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String(), "from", from, "limit", limit)
+
+	// get the namespace's shared service bus client
+	client, release, err := clients.Client(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get service bus client", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get service bus client")
+		return
+	}
+	defer release()
+
+	// peek dead letter messages
+	start := time.Now()
+	messages, err := servicebus.PeekDeadLetterMessages(r.Context(), client, entity, from, limit)
+	observeOperation(metricsOperationPeek, start, err)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to peek dead letter messages", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to peek dead letter messages")
+		return
+	}
+
+	// map to JSON-serializable page, with a cursor if there may be more messages
+	page := &servicebus.DeadLetterMessagePage{
+		Messages: make([]*servicebus.DeadLetterMessage, 0, len(messages)),
+	}
+	event := auditEvent(r.Context())
+	for _, message := range messages {
+		page.Messages = append(page.Messages, servicebus.NewDeadLetterMessage(namespace, entity, message, bodyOptions))
+		event.Messages = append(event.Messages, AuditMessage{MessageID: message.MessageID, SequenceNumber: message.SequenceNumber})
+	}
+	if len(messages) == limit {
+		next := *messages[len(messages)-1].SequenceNumber + 1
+		page.Next = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wirePage(page))
+}
+
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// extract query parameters
+	namespace := r.URL.Query().Get("namespace")
+	entity, err := entityFromQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	options := &servicebus.StatsOptions{
+		Namespace:   namespace,
+		MaxMessages: defaultStatsScanLimit,
+		Redactor:    redactor,
+	}
+	if v := r.URL.Query().Get("max-messages"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxStatsScanLimit {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("max-messages must be between 1 and %d", maxStatsScanLimit))
+			return
+		}
+		options.MaxMessages = parsed
+	}
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed < time.Minute {
+			respondError(w, http.StatusBadRequest, "bucket must be a duration of at least 1m")
+			return
+		}
+		options.BucketSize = parsed
+	}
+	if v := r.URL.Query().Get("top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			respondError(w, http.StatusBadRequest, "top must be a non-negative number")
+			return
+		}
+		options.Top = parsed
+	}
+
+	slog.InfoContext(r.Context(), "received stats request", "principal", PrincipalFromContext(r.Context()).String(), "namespace", namespace, "entity", entity.String(), "max-messages", options.MaxMessages)
+
+	// get the namespace's shared service bus client
+	client, release, err := clients.Client(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get service bus client", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get service bus client")
+		return
+	}
+	defer release()
+
+	// aggregate the DLQ by peeking through it
+	start := time.Now()
+	stats, err := servicebus.ScanDeadLetterStats(r.Context(), client, entity, options)
+	observeOperation(metricsOperationStats, start, err)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to scan dead letter queue", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to scan dead letter queue")
+		return
+	}
+
+	// runtime counts need management rights, so stats are still returned without them
+	stats.Runtime = runtimeCounts(r.Context(), namespace, entity)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wireStats(stats))
+}
+
+// runtimeCounts reads the entity's runtime counts, returning nil when they aren't available
+func runtimeCounts(ctx context.Context, namespace string, entity servicebus.Entity) *servicebus.RuntimeCounts {
+	adminClient, err := clients.AdminClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get service bus admin client", "error", err)
+		return nil
+	}
+
+	start := time.Now()
+	counts, err := servicebus.GetRuntimeCounts(ctx, adminClient, entity)
+	observeOperation(metricsOperationRuntimeCounts, start, err)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get runtime counts", "entity", entity.String(), "error", err)
+		return nil
+	}
+	return counts
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetriggerHandlerBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "InvalidJSON", body: `{"message-id":`, wantStatus: http.StatusBadRequest, wantMessage: "invalid JSON"},
+		{name: "TooLarge", body: `{"all": true, "annotations": {"note": "` + strings.Repeat("x", maxRetriggerBodySize) + `"}}`, wantStatus: http.StatusRequestEntityTooLarge, wantMessage: "must not exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/retrigger?namespace=sb-payments-dev&queue=orders", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			retriggerHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMessage) {
+				t.Errorf("expected %q in body, got %s", tt.wantMessage, rec.Body.String())
+			}
+		})
+	}
+}
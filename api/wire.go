@@ -0,0 +1,74 @@
+package main
+
+import (
+	"dlqt/client"
+	"dlqt/internal/servicebus"
+)
+
+// the API responds with the client's types, so the JSON the client decodes is the JSON the server encodes
+
+// wireMessage converts a dead letter message, the conversion stops compiling if the fields drift apart
+func wireMessage(message *servicebus.DeadLetterMessage) *client.DeadLetterMessage {
+	return (*client.DeadLetterMessage)(message)
+}
+
+func wirePage(page *servicebus.DeadLetterMessagePage) *client.DeadLetterMessagePage {
+	wire := &client.DeadLetterMessagePage{
+		Messages: make([]*client.DeadLetterMessage, len(page.Messages)),
+		Next:     page.Next,
+	}
+	for i, message := range page.Messages {
+		wire.Messages[i] = wireMessage(message)
+	}
+	return wire
+}
+
+func wireBulkRetriggerResult(result *servicebus.BulkRetriggerResult) *client.BulkRetriggerResult {
+	wire := &client.BulkRetriggerResult{DryRun: result.DryRun, Retriggered: result.Retriggered}
+	for _, reference := range result.Matched {
+		wire.Matched = append(wire.Matched, client.MessageReference(reference))
+	}
+	if result.Matched != nil && wire.Matched == nil {
+		wire.Matched = []client.MessageReference{}
+	}
+	return wire
+}
+
+func wireStats(stats *servicebus.DeadLetterStats) *client.DeadLetterStats {
+	wire := &client.DeadLetterStats{
+		Namespace:          stats.Namespace,
+		Queue:              stats.Queue,
+		Topic:              stats.Topic,
+		Subscription:       stats.Subscription,
+		Scanned:            stats.Scanned,
+		Truncated:          stats.Truncated,
+		OldestEnqueuedTime: stats.OldestEnqueuedTime,
+		NewestEnqueuedTime: stats.NewestEnqueuedTime,
+		BucketSize:         stats.BucketSize,
+		ByReason:           wireValueCounts(stats.ByReason),
+		ByErrorDescription: wireValueCounts(stats.ByErrorDescription),
+		BySubject:          wireValueCounts(stats.BySubject),
+	}
+	if stats.Runtime != nil {
+		runtime := client.RuntimeCounts(*stats.Runtime)
+		wire.Runtime = &runtime
+	}
+	if stats.ByEnqueuedTime != nil {
+		wire.ByEnqueuedTime = make([]client.TimeBucket, len(stats.ByEnqueuedTime))
+	}
+	for i, bucket := range stats.ByEnqueuedTime {
+		wire.ByEnqueuedTime[i] = client.TimeBucket(bucket)
+	}
+	return wire
+}
+
+func wireValueCounts(counts []servicebus.ValueCount) []client.ValueCount {
+	if counts == nil {
+		return nil
+	}
+	wire := make([]client.ValueCount, len(counts))
+	for i, count := range counts {
+		wire[i] = client.ValueCount(count)
+	}
+	return wire
+}
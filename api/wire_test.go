@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"dlqt/internal/servicebus"
+)
+
+func TestWireTypes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reason := "MaxDeliveryCountExceeded"
+	scheduled := int32(1)
+	size := int64(2048)
+
+	stats := &servicebus.DeadLetterStats{
+		Namespace:          "sb",
+		Queue:              "orders",
+		Runtime:            &servicebus.RuntimeCounts{ActiveMessageCount: 1, DeadLetterMessageCount: 2, ScheduledMessageCount: &scheduled, TotalMessageCount: 3, SizeInBytes: &size},
+		Scanned:            2,
+		Truncated:          true,
+		OldestEnqueuedTime: &now,
+		NewestEnqueuedTime: &now,
+		BucketSize:         "1h0m0s",
+		ByReason:           []servicebus.ValueCount{{Value: reason, Count: 2}},
+		ByErrorDescription: []servicebus.ValueCount{},
+		ByEnqueuedTime:     []servicebus.TimeBucket{{Start: now, Count: 2}},
+	}
+	result := &servicebus.BulkRetriggerResult{
+		Matched:     []servicebus.MessageReference{{MessageID: "m1", SequenceNumber: 1, DeadLetterReason: &reason}},
+		Retriggered: 1,
+	}
+	page := &servicebus.DeadLetterMessagePage{
+		Messages: []*servicebus.DeadLetterMessage{{MessageID: "m1", DeadLetterReason: &reason, ApplicationProperties: map[string]any{"a": "b"}}},
+	}
+
+	tests := []struct {
+		name       string
+		value, got any
+	}{
+		{name: "Stats", value: stats, got: wireStats(stats)},
+		{name: "StatsEmpty", value: &servicebus.DeadLetterStats{}, got: wireStats(&servicebus.DeadLetterStats{})},
+		{name: "BulkRetriggerResult", value: result, got: wireBulkRetriggerResult(result)},
+		{name: "BulkRetriggerResultEmpty", value: &servicebus.BulkRetriggerResult{DryRun: true, Matched: []servicebus.MessageReference{}}, got: wireBulkRetriggerResult(&servicebus.BulkRetriggerResult{DryRun: true, Matched: []servicebus.MessageReference{}})},
+		{name: "Page", value: page, got: wirePage(page)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			got, err := json.Marshal(tt.got)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Package client is a typed Go client for the dlqt API
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// defaults for zero Options fields
+const (
+	// DefaultTimeout bounds each attempt, it matches the API's write timeout so long bulk retriggers can finish
+	DefaultTimeout    = 5 * time.Minute
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+	DefaultUserAgent  = "dlqt-client"
+)
+
+// maxRetryDelay caps the exponential backoff and Retry-After delays
+const maxRetryDelay = 30 * time.Second
+
+// TokenSource provides the bearer token of each request, it is called again for every retry
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken always returns the same token, e.g. one acquired by a workload identity elsewhere
+func StaticToken(token string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (string, error) { return token, nil })
+}
+
+// Options configures New, zero fields use the defaults
+type Options struct {
+	// HTTPClient sends the requests, nil uses a client propagating the trace context
+	HTTPClient *http.Client
+	// Timeout bounds each attempt, 0 uses DefaultTimeout
+	Timeout time.Duration
+	// MaxRetries of read requests failing with a network error, 429 or 502 to 504. Retriggers are never retried as
+	// they aren't idempotent. 0 uses DefaultMaxRetries, negative disables retries.
+	MaxRetries int
+	// RetryDelay is the first backoff delay, doubled for each retry, 0 uses DefaultRetryDelay
+	RetryDelay time.Duration
+	// UserAgent identifies the caller, empty uses DefaultUserAgent
+	UserAgent string
+}
+
+// Client calls the dlqt API, it is safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	tokens     TokenSource
+	httpClient *http.Client
+	timeout    time.Duration
+	maxRetries int
+	retryDelay time.Duration
+	userAgent  string
+}
+
+// New creates a client for the API at baseURL, e.g. https://dlqt.example.com
+func New(baseURL string, tokens TokenSource, options *Options) (*Client, error) {
+	if tokens == nil {
+		return nil, errors.New("token source is required")
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid API URL '%s', expected an http or https URL", baseURL)
+	}
+	if options == nil {
+		options = &Options{}
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		tokens:     tokens,
+		httpClient: options.HTTPClient,
+		timeout:    options.Timeout,
+		maxRetries: options.MaxRetries,
+		retryDelay: options.RetryDelay,
+		userAgent:  options.UserAgent,
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	}
+	if c.timeout == 0 {
+		c.timeout = DefaultTimeout
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.retryDelay == 0 {
+		c.retryDelay = DefaultRetryDelay
+	}
+	if c.userAgent == "" {
+		c.userAgent = DefaultUserAgent
+	}
+	return c, nil
+}
+
+// BodyOptions controls how message bodies are rendered by the API
+type BodyOptions struct {
+	// Compact disables the API's pretty-printing of JSON bodies
+	Compact bool
+	// MaxBodySize truncates bodies to this many bytes, 0 means no limit
+	MaxBodySize int
+	// Decoder renders bodies into DecodedBody, e.g. "auto", empty leaves them undecoded
+	Decoder string
+}
+
+func (o *BodyOptions) addParams(params url.Values) {
+	if o == nil {
+		return
+	}
+	if o.Compact {
+		params.Set("pretty", "false")
+	}
+	if o.MaxBodySize > 0 {
+		params.Set("max-body-size", strconv.Itoa(o.MaxBodySize))
+	}
+	if o.Decoder != "" {
+		params.Set("decoder", o.Decoder)
+	}
+}
+
+// PeekOptions selects a page of dead letters
+type PeekOptions struct {
+	BodyOptions
+	// From is the sequence number to start at, use DeadLetterMessagePage.Next to continue
+	From int64
+	// Limit is the page size, 0 uses the API's default
+	Limit int
+}
+
+// StatsOptions controls the DLQ scan, zero fields use the API's defaults
+type StatsOptions struct {
+	// MaxMessages stops the scan after this many messages
+	MaxMessages int
+	// BucketSize of the enqueued-time histogram
+	BucketSize time.Duration
+	// Top keeps only the most common values of each count, 0 keeps all
+	Top int
+}
+
+// RetriggerRequest selects a single message by MessageID or SequenceNumber
+type RetriggerRequest struct {
+	MessageID      string `json:"message-id,omitempty"`
+	SequenceNumber *int64 `json:"sequence-number,omitempty"`
+	// TargetSubscription marks a message re-published to a topic as meant only for the source subscription
+	TargetSubscription bool `json:"target-subscription,omitempty"`
+	// Annotations are extra application properties set on the retriggered message, without the dlqt- prefix
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// DisableAnnotations skips the dlqt-* properties recording the retrigger
+	DisableAnnotations bool `json:"disable-annotations,omitempty"`
+}
+
+// BulkRetriggerRequest retriggers every dead letter matching the filter
+type BulkRetriggerRequest struct {
+	Filter *RetriggerFilter `json:"filter,omitempty"`
+	// Limit caps the number of matched messages, 0 means no limit
+	Limit int `json:"limit,omitempty"`
+	// DryRun only reports the matched messages
+	DryRun             bool              `json:"dry-run,omitempty"`
+	TargetSubscription bool              `json:"target-subscription,omitempty"`
+	Annotations        map[string]string `json:"annotations,omitempty"`
+	DisableAnnotations bool              `json:"disable-annotations,omitempty"`
+}
+
+// RetriggerFilter selects dead letters, empty fields match everything
+type RetriggerFilter struct {
+	Reason                string            `json:"reason,omitempty"`
+	ErrorDescription      string            `json:"error-description,omitempty"`
+	Since                 *time.Time        `json:"since,omitempty"`
+	Until                 *time.Time        `json:"until,omitempty"`
+	Subject               string            `json:"subject,omitempty"`
+	ApplicationProperties map[string]string `json:"application-properties,omitempty"`
+}
+
+// Fetch returns the first message of the dead letter queue
+func (c *Client) Fetch(ctx context.Context, namespace string, entity Entity, options *BodyOptions) (*DeadLetterMessage, error) {
+	params := entityParams(namespace, entity)
+	options.addParams(params)
+	var message DeadLetterMessage
+	if err := c.do(ctx, http.MethodGet, "/fetch", params, nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Peek returns a page of dead letters without locking them
+func (c *Client) Peek(ctx context.Context, namespace string, entity Entity, options *PeekOptions) (*DeadLetterMessagePage, error) {
+	params := entityParams(namespace, entity)
+	if options != nil {
+		options.BodyOptions.addParams(params)
+		params.Set("from", strconv.FormatInt(options.From, 10))
+		if options.Limit > 0 {
+			params.Set("limit", strconv.Itoa(options.Limit))
+		}
+	}
+	var page DeadLetterMessagePage
+	if err := c.do(ctx, http.MethodGet, "/messages", params, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Stats scans the dead letter queue and returns counts by reason, error description, subject and enqueued time
+func (c *Client) Stats(ctx context.Context, namespace string, entity Entity, options *StatsOptions) (*DeadLetterStats, error) {
+	params := entityParams(namespace, entity)
+	if options != nil {
+		if options.MaxMessages > 0 {
+			params.Set("max-messages", strconv.Itoa(options.MaxMessages))
+		}
+		if options.BucketSize > 0 {
+			params.Set("bucket", options.BucketSize.String())
+		}
+		if options.Top > 0 {
+			params.Set("top", strconv.Itoa(options.Top))
+		}
+	}
+	var stats DeadLetterStats
+	if err := c.do(ctx, http.MethodGet, "/stats", params, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Retrigger moves a single dead letter back to its entity, returning the API's confirmation
+func (c *Client) Retrigger(ctx context.Context, namespace string, entity Entity, request *RetriggerRequest) (string, error) {
+	if request == nil || (request.MessageID == "") == (request.SequenceNumber == nil) {
+		return "", errors.New("exactly one of message ID or sequence number is required")
+	}
+	var response struct {
+		Message string `json:"message"`
+	}
+	if err := c.do(ctx, http.MethodPatch, "/retrigger", entityParams(namespace, entity), request, &response); err != nil {
+		return "", err
+	}
+	return response.Message, nil
+}
+
+// BulkRetrigger moves every matching dead letter back to its entity
+func (c *Client) BulkRetrigger(ctx context.Context, namespace string, entity Entity, request *BulkRetriggerRequest) (*BulkRetriggerResult, error) {
+	if request == nil {
+		request = &BulkRetriggerRequest{}
+	}
+	body := struct {
+		All bool `json:"all"`
+		*BulkRetriggerRequest
+	}{true, request}
+	var result BulkRetriggerResult
+	if err := c.do(ctx, http.MethodPatch, "/retrigger", entityParams(namespace, entity), body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func entityParams(namespace string, entity Entity) url.Values {
+	params := url.Values{}
+	params.Set("namespace", namespace)
+	if entity.IsSubscription() {
+		params.Set("topic", entity.Topic)
+		params.Set("subscription", entity.Subscription)
+	} else {
+		params.Set("queue", entity.Queue)
+	}
+	return params
+}
+
+// do sends the request, retrying GETs, and decodes a successful JSON response into out. Every attempt carries the
+// same X-Request-ID so the API's logs of a retried call can be correlated.
+func (c *Client) do(ctx context.Context, method string, path string, params url.Values, in any, out any) error {
+	endpoint := c.baseURL.JoinPath(path)
+	endpoint.RawQuery = params.Encode()
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+	}
+	requestID := newRequestID()
+
+	retries := 0
+	if method == http.MethodGet {
+		retries = c.maxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		retry, retryAfter, err := c.attempt(ctx, method, endpoint.String(), requestID, body, out)
+		if err == nil || !retry || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := min(c.retryDelay<<attempt, maxRetryDelay)
+		if retryAfter > 0 {
+			delay = min(retryAfter, maxRetryDelay)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt sends the request once, reporting whether a failure may succeed when repeated and the Retry-After delay
+// of a failed response
+func (c *Client) attempt(ctx context.Context, method string, endpoint string, requestID string, body []byte, out any) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("X-Request-ID", requestID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// network errors, including the attempt timing out
+		return true, 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, parseRetryAfter(resp.Header.Get("Retry-After")), newAPIError(resp)
+		}
+		return false, 0, newAPIError(resp)
+	}
+	if out == nil {
+		return false, 0, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, 0, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return false, 0, nil
+}
+
+// parseRetryAfter reads a Retry-After header in seconds, the HTTP-date form isn't sent by the API or Azure
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newRequestID generates the X-Request-ID shared by all attempts of a call
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient serves handler and returns a client for it with short retry delays
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL, StaticToken("token"), &Options{RetryDelay: time.Millisecond, UserAgent: "test-agent"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		tokens  TokenSource
+		wantErr bool
+	}{
+		{"Valid", "https://dlqt.example.com", StaticToken("token"), false},
+		{"NoTokenSource", "https://dlqt.example.com", nil, true},
+		{"NoScheme", "dlqt.example.com", StaticToken("token"), true},
+		{"InvalidURL", "https://dlqt.example.com/%", StaticToken("token"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.baseURL, tt.tokens, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/fetch" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("expected bearer token, got %q", got)
+		}
+		if got := r.Header.Get("User-Agent"); got != "test-agent" {
+			t.Errorf("expected user agent, got %q", got)
+		}
+		want := "decoder=auto&max-body-size=10&namespace=ns&pretty=false&subscription=sub&topic=orders"
+		if r.URL.RawQuery != want {
+			t.Errorf("expected query %s, got %s", want, r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"messageID":"m1","sequenceNumber":7}`))
+	})
+
+	message, err := c.Fetch(context.Background(), "ns", SubscriptionEntity("orders", "sub"), &BodyOptions{Compact: true, MaxBodySize: 10, Decoder: "auto"})
+	if err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	if message.MessageID != "m1" || message.SequenceNumber == nil || *message.SequenceNumber != 7 {
+		t.Errorf("unexpected message: %+v", message)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if want := "from=5&limit=2&namespace=ns&queue=orders"; r.URL.Path != "/messages" || r.URL.RawQuery != want {
+			t.Errorf("expected /messages?%s, got %s", want, r.URL)
+		}
+		w.Write([]byte(`{"messages":[{"messageID":"a"},{"messageID":"b"}],"next":9}`))
+	})
+
+	page, err := c.Peek(context.Background(), "ns", QueueEntity("orders"), &PeekOptions{From: 5, Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to peek: %v", err)
+	}
+	if len(page.Messages) != 2 || page.Next == nil || *page.Next != 9 {
+		t.Errorf("unexpected page: %+v", page)
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if want := "bucket=30m0s&max-messages=100&namespace=ns&queue=orders&top=3"; r.URL.RawQuery != want {
+			t.Errorf("expected query %s, got %s", want, r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"scanned":4}`))
+	})
+
+	stats, err := c.Stats(context.Background(), "ns", QueueEntity("orders"), &StatsOptions{MaxMessages: 100, BucketSize: 30 * time.Minute, Top: 3})
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Scanned != 4 {
+		t.Errorf("expected 4 scanned messages, got %d", stats.Scanned)
+	}
+}
+
+func TestRetrigger(t *testing.T) {
+	sequenceNumber := int64(42)
+	tests := []struct {
+		name     string
+		request  *RetriggerRequest
+		wantBody string
+		wantErr  bool
+	}{
+		{"MessageID", &RetriggerRequest{MessageID: "m1"}, `{"message-id":"m1"}`, false},
+		{"SequenceNumber", &RetriggerRequest{SequenceNumber: &sequenceNumber, TargetSubscription: true}, `{"sequence-number":42,"target-subscription":true}`, false},
+		{"Annotations", &RetriggerRequest{MessageID: "m1", Annotations: map[string]string{"ticket": "INC-1"}, DisableAnnotations: true}, `{"message-id":"m1","annotations":{"ticket":"INC-1"},"disable-annotations":true}`, false},
+		{"Neither", &RetriggerRequest{}, "", true},
+		{"Both", &RetriggerRequest{MessageID: "m1", SequenceNumber: &sequenceNumber}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				var body json.RawMessage
+				json.NewDecoder(r.Body).Decode(&body)
+				if r.Method != http.MethodPatch || string(body) != tt.wantBody {
+					t.Errorf("expected PATCH with %s, got %s with %s", tt.wantBody, r.Method, body)
+				}
+				w.Write([]byte(`{"message":"retriggered"}`))
+			})
+
+			message, err := c.Retrigger(context.Background(), "ns", QueueEntity("orders"), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && message != "retriggered" {
+				t.Errorf("expected confirmation, got %q", message)
+			}
+		})
+	}
+}
+
+func TestBulkRetrigger(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		if want := `{"all":true,"filter":{"reason":"MaxDeliveryCountExceeded"},"limit":10,"dry-run":true}`; string(body) != want {
+			t.Errorf("expected body %s, got %s", want, body)
+		}
+		w.Write([]byte(`{"dryRun":true,"matched":[{"messageID":"m1","sequenceNumber":1}],"retriggered":0}`))
+	})
+
+	result, err := c.BulkRetrigger(context.Background(), "ns", QueueEntity("orders"), &BulkRetriggerRequest{
+		Filter: &RetriggerFilter{Reason: "MaxDeliveryCountExceeded"},
+		Limit:  10,
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to retrigger: %v", err)
+	}
+	if !result.DryRun || len(result.Matched) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantSummary string
+		wantMessage string
+		wantIs      error
+	}{
+		{"JSON", http.StatusNotFound, "application/json", `{"error":"no messages","message":"the DLQ is empty"}`, "no messages", "the DLQ is empty", ErrNotFound},
+		{"PlainText", http.StatusUnauthorized, "text/plain", "invalid token\n", "invalid token", "", ErrUnauthorized},
+		{"Forbidden", http.StatusForbidden, "application/json", `{"error":"access denied by policy"}`, "access denied by policy", "", ErrForbidden},
+		{"BadRequest", http.StatusBadRequest, "application/json", `{"error":"queue or topic is required"}`, "queue or topic is required", "", ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := c.Fetch(context.Background(), "ns", QueueEntity("orders"), nil)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Summary != tt.wantSummary || apiErr.Message != tt.wantMessage || apiErr.RequestID == "" {
+				t.Errorf("unexpected error: %+v", apiErr)
+			}
+			if !errors.Is(err, tt.wantIs) || errors.Is(err, ErrUnavailable) {
+				t.Errorf("expected %v to match only %v", err, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		wantAttempts int32
+		wantErr      error
+	}{
+		{"RetriedUntilSuccess", http.MethodGet, []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, 3, nil},
+		{"RetriesExhausted", http.MethodGet, []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}, 4, ErrUnavailable},
+		{"ClientErrorNotRetried", http.MethodGet, []int{http.StatusForbidden, http.StatusOK}, 1, ErrForbidden},
+		{"RetriggerNotRetried", http.MethodPatch, []int{http.StatusServiceUnavailable, http.StatusOK}, 1, ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			requestIDs := map[string]bool{}
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				requestIDs[r.Header.Get("X-Request-ID")] = true
+				w.WriteHeader(tt.statuses[attempt-1])
+				w.Write([]byte(`{"message":"ok"}`))
+			})
+
+			var err error
+			if tt.method == http.MethodGet {
+				_, err = c.Fetch(context.Background(), "ns", QueueEntity("orders"), nil)
+			} else {
+				_, err = c.Retrigger(context.Background(), "ns", QueueEntity("orders"), &RetriggerRequest{MessageID: "m1"})
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+			if len(requestIDs) != 1 {
+				t.Errorf("expected every attempt to share a request ID, got %v", requestIDs)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	start := time.Now()
+	if _, err := c.Fetch(context.Background(), "ns", QueueEntity("orders"), nil); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the Retry-After delay to be honored, retried after %v", elapsed)
+	}
+}
+
+func TestTokenSourceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request without a token")
+	}))
+	defer server.Close()
+	c, err := New(server.URL, TokenSourceFunc(func(context.Context) (string, error) {
+		return "", errors.New("not signed in")
+	}), nil)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = c.Fetch(context.Background(), "ns", QueueEntity("orders"), nil)
+	if err == nil || !strings.Contains(err.Error(), "not signed in") {
+		t.Errorf("expected the token error, got %v", err)
+	}
+}
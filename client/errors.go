@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sentinel errors matched by APIError, e.g. errors.Is(err, client.ErrNotFound)
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("unavailable")
+)
+
+// maxErrorBodySize bounds how much of an error response is read
+const maxErrorBodySize = 64 << 10
+
+// APIError is a non-2xx response of the API
+type APIError struct {
+	StatusCode int
+	// Summary is the error field of the API's JSON error response, or the plain text body of errors raised before
+	// the handlers such as authentication failures
+	Summary string
+	// Message carries the detail of the JSON error response, if any
+	Message string
+	// RequestID is the X-Request-ID the API logged the request under
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	text := fmt.Sprintf("dlqt API returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Summary != "" {
+		text += ": " + e.Summary
+	}
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		text += " (request ID " + e.RequestID + ")"
+	}
+	return text
+}
+
+// Is matches the sentinel error of the status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusBadGateway || e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// newAPIError decodes the API's JSON error response, falling back to the text body
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	var response struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil && response.Error != "" {
+		apiErr.Summary = response.Error
+		apiErr.Message = response.Message
+	} else {
+		apiErr.Summary = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
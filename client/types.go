@@ -0,0 +1,145 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// encodings of DeadLetterMessage.Body, an empty encoding is utf8
+const (
+	BodyEncodingUTF8   = "utf8"
+	BodyEncodingBase64 = "base64"
+)
+
+// Entity is a queue or topic subscription, the dead letter queue of which the API reads
+type Entity struct {
+	Queue        string
+	Topic        string
+	Subscription string
+}
+
+// QueueEntity targets a queue
+func QueueEntity(queue string) Entity {
+	return Entity{Queue: queue}
+}
+
+// SubscriptionEntity targets a topic subscription
+func SubscriptionEntity(topic string, subscription string) Entity {
+	return Entity{Topic: topic, Subscription: subscription}
+}
+
+// IsSubscription reports whether the entity is a topic subscription
+func (e Entity) IsSubscription() bool {
+	return e.Topic != ""
+}
+
+// String describes the entity for logs and errors
+func (e Entity) String() string {
+	if e.IsSubscription() {
+		return fmt.Sprintf("topic '%s' subscription '%s'", e.Topic, e.Subscription)
+	}
+	return fmt.Sprintf("queue '%s'", e.Queue)
+}
+
+// DeadLetterMessage is a dead letter message as returned by the API
+type DeadLetterMessage struct {
+	Namespace                  string         `json:"namespace"`
+	Queue                      string         `json:"queue,omitempty"`
+	Topic                      string         `json:"topic,omitempty"`
+	Subscription               string         `json:"subscription,omitempty"`
+	MessageID                  string         `json:"messageID"`
+	Body                       string         `json:"body"`
+	BodyEncoding               string         `json:"bodyEncoding,omitempty"`
+	BodySize                   int            `json:"bodySize"`
+	BodyTruncated              bool           `json:"bodyTruncated,omitempty"`
+	DecodedBody                string         `json:"decodedBody,omitempty"`
+	DecodedBodyTruncated       bool           `json:"decodedBodyTruncated,omitempty"`
+	Decoders                   []string       `json:"decoders,omitempty"`
+	DecodeError                string         `json:"decodeError,omitempty"`
+	ContentType                *string        `json:"contentType,omitempty"`
+	CorrelationID              *string        `json:"correlationID,omitempty"`
+	DeadLetterErrorDescription *string        `json:"deadLetterErrorDescription,omitempty"`
+	DeadLetterReason           *string        `json:"deadLetterReason,omitempty"`
+	DeadLetterSource           *string        `json:"deadLetterSource,omitempty"`
+	DeliveryCount              uint32         `json:"deliveryCount"`
+	EnqueuedSequenceNumber     *int64         `json:"enqueuedSequenceNumber,omitempty"`
+	EnqueuedTime               *time.Time     `json:"enqueuedTime,omitempty"`
+	ExpiresAt                  *time.Time     `json:"expiresAt,omitempty"`
+	LockedUntil                *time.Time     `json:"lockedUntil,omitempty"`
+	PartitionKey               *string        `json:"partitionKey,omitempty"`
+	ReplyTo                    *string        `json:"replyTo,omitempty"`
+	ReplyToSessionID           *string        `json:"replyToSessionID,omitempty"`
+	ScheduledEnqueueTime       *time.Time     `json:"scheduledEnqueueTime,omitempty"`
+	SequenceNumber             *int64         `json:"sequenceNumber,omitempty"`
+	SessionID                  *string        `json:"sessionID,omitempty"`
+	State                      int32          `json:"state"`
+	Subject                    *string        `json:"subject,omitempty"`
+	TimeToLive                 *time.Duration `json:"timeToLive,omitempty"`
+	To                         *string        `json:"to,omitempty"`
+	ApplicationProperties      map[string]any `json:"applicationProperties,omitempty"`
+	// ApplicationPropertyTypes records the Go type of non-string properties in exports
+	ApplicationPropertyTypes map[string]string `json:"applicationPropertyTypes,omitempty"`
+	// Redacted marks exports written with redaction
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// DeadLetterMessagePage is a page of peeked messages, Next is the sequence number to continue peeking from
+type DeadLetterMessagePage struct {
+	Messages []*DeadLetterMessage `json:"messages"`
+	Next     *int64               `json:"next,omitempty"`
+}
+
+// MessageReference identifies a dead letter message matched by a bulk retrigger
+type MessageReference struct {
+	MessageID        string  `json:"messageID"`
+	SequenceNumber   int64   `json:"sequenceNumber"`
+	DeadLetterReason *string `json:"deadLetterReason,omitempty"`
+}
+
+// BulkRetriggerResult lists the matched messages and how many were retriggered
+type BulkRetriggerResult struct {
+	DryRun      bool               `json:"dryRun"`
+	Matched     []MessageReference `json:"matched"`
+	Retriggered int                `json:"retriggered"`
+}
+
+// RuntimeCounts of a queue or subscription, scheduled count and size are only reported for queues
+type RuntimeCounts struct {
+	ActiveMessageCount             int32  `json:"activeMessageCount"`
+	DeadLetterMessageCount         int32  `json:"deadLetterMessageCount"`
+	ScheduledMessageCount          *int32 `json:"scheduledMessageCount,omitempty"`
+	TransferMessageCount           int32  `json:"transferMessageCount"`
+	TransferDeadLetterMessageCount int32  `json:"transferDeadLetterMessageCount"`
+	TotalMessageCount              int64  `json:"totalMessageCount"`
+	SizeInBytes                    *int64 `json:"sizeInBytes,omitempty"`
+}
+
+// ValueCount is the number of dead letter messages sharing a value
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// TimeBucket is the number of dead letter messages enqueued in [Start, Start+bucket size)
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// DeadLetterStats are the statistics of a dead letter queue, Truncated means the scan stopped before the end of the DLQ
+type DeadLetterStats struct {
+	Namespace          string         `json:"namespace"`
+	Queue              string         `json:"queue,omitempty"`
+	Topic              string         `json:"topic,omitempty"`
+	Subscription       string         `json:"subscription,omitempty"`
+	Runtime            *RuntimeCounts `json:"runtime,omitempty"`
+	Scanned            int            `json:"scanned"`
+	Truncated          bool           `json:"truncated,omitempty"`
+	OldestEnqueuedTime *time.Time     `json:"oldestEnqueuedTime,omitempty"`
+	NewestEnqueuedTime *time.Time     `json:"newestEnqueuedTime,omitempty"`
+	BucketSize         string         `json:"bucketSize"`
+	ByReason           []ValueCount   `json:"byReason"`
+	ByErrorDescription []ValueCount   `json:"byErrorDescription"`
+	BySubject          []ValueCount   `json:"bySubject"`
+	ByEnqueuedTime     []TimeBucket   `json:"byEnqueuedTime"`
+}
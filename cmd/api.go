@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"dlqt/client"
+	"dlqt/internal/msal"
+	"dlqt/internal/redact"
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+// newAPIClient creates a dlqt API client authenticating the signed-in user with MSAL
+func newAPIClient(cmd *cli.Command) (*client.Client, error) {
+	msalConfig := msal.MSALConfig{
+		TenantID:  cmd.String("cmd-tenant-id"),
+		ClientID:  cmd.String("cmd-client-id"),
+		Scope:     "api://" + cmd.String("api-client-id") + "/dlq.read",
+		CacheFile: "msal_cache.json",
+	}
+	tokens := client.TokenSourceFunc(func(ctx context.Context) (string, error) {
+		token, err := msal.GetToken(ctx, &msalConfig)
+		if err != nil {
+			return "", err
+		}
+		slog.DebugContext(ctx, "acquired token", "token", redact.Token(token))
+		return token, nil
+	})
+
+	apiClient, err := client.New(cmd.String("api-url"), tokens, &client.Options{
+		UserAgent: "dlqt/" + cmd.Root().Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	return apiClient, nil
+}
+
+// apiEntityFromFlags is the entity of the flags as the API client names it
+func apiEntityFromFlags(cmd *cli.Command) (client.Entity, error) {
+	entity, err := entityFromFlags(cmd)
+	if err != nil {
+		return client.Entity{}, err
+	}
+	return client.Entity{Queue: entity.Queue, Topic: entity.Topic, Subscription: entity.Subscription}, nil
+}
+
+// fromAPIMessages converts messages returned by the API so they can be decoded, redacted and printed,
+// the conversion stops compiling if the client and server message fields drift apart
+func fromAPIMessages(messages []*client.DeadLetterMessage) []*servicebus.DeadLetterMessage {
+	converted := make([]*servicebus.DeadLetterMessage, len(messages))
+	for i, message := range messages {
+		converted[i] = (*servicebus.DeadLetterMessage)(message)
+	}
+	return converted
+}
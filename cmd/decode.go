@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dlqt/internal/decode"
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+// decoderFlags are shared by the commands that show message bodies
+func decoderFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "decoder",
+			Usage:    "the decoder for message bodies: auto picks one by the dlqt-decoder property or content type, none disables decoding",
+			Value:    decode.Auto,
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "protobuf-descriptor-set",
+			Usage:    "a protobuf FileDescriptorSet file, as written by protoc --descriptor_set_out --include_imports",
+			Sources:  cli.EnvVars("DLQT_PROTOBUF_DESCRIPTOR_SET"),
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "protobuf-message",
+			Usage:    "the fully-qualified protobuf message type, when the content type doesn't name one",
+			Sources:  cli.EnvVars("DLQT_PROTOBUF_MESSAGE"),
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "avro-schema",
+			Usage:    "an Avro schema file in JSON form",
+			Sources:  cli.EnvVars("DLQT_AVRO_SCHEMA"),
+			Required: false,
+		},
+	}
+}
+
+// newDecoders loads the decoders configured by flags, nil when decoding is disabled
+func newDecoders(cmd *cli.Command) (*decode.Registry, error) {
+	name := cmd.String("decoder")
+	if name == decode.None {
+		return nil, nil
+	}
+
+	registry, err := decode.NewConfiguredRegistry(decode.Config{
+		ProtobufDescriptorSet: cmd.String("protobuf-descriptor-set"),
+		ProtobufMessage:       cmd.String("protobuf-message"),
+		AvroSchema:            cmd.String("avro-schema"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load decoders: %w", err)
+	}
+	if _, ok := registry.Lookup(name); !ok && name != decode.Auto {
+		return nil, fmt.Errorf("unknown decoder '%s', expected %s, %s or one of %s", name, decode.Auto, decode.None, strings.Join(registry.Names(), ", "))
+	}
+	return registry, nil
+}
+
+// decodeMessages renders the bodies of messages received from the API
+func decodeMessages(registry *decode.Registry, name string, messages []*servicebus.DeadLetterMessage) {
+	if registry == nil {
+		return
+	}
+	for _, message := range messages {
+		message.Decode(registry, name)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+// entityFromFlags reads either --queue, or --topic and --subscription
+func entityFromFlags(cmd *cli.Command) (servicebus.Entity, error) {
+	entity := servicebus.Entity{
+		Queue:        cmd.String("queue"),
+		Topic:        cmd.String("topic"),
+		Subscription: cmd.String("subscription"),
+	}
+	return entity, entity.Validate()
+}
+
+// detectSession looks up whether the entity requires sessions via the admin client, falling back to the entity as
+// given so callers with only data-plane roles, without Manage rights, can still use non-session entities
+func detectSession(ctx context.Context, namespace string, entity servicebus.Entity) servicebus.Entity {
+	adminClient, err := servicebus.GetAdminClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get Service Bus admin client, assuming no sessions", "error", err)
+		return entity
+	}
+
+	detected, err := servicebus.DetectSession(ctx, adminClient, entity)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to detect session-enabled entity, assuming no sessions", "entity", entity.String(), "error", err)
+		return entity
+	}
+	slog.DebugContext(ctx, "detected session support", "requiresSession", detected.RequiresSession)
+	return detected
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+func exportMessages(ctx context.Context, cmd *cli.Command) (err error) {
+	namespace := cmd.String("namespace")
+	entity, err := entityFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	file := cmd.String("file")
+	compress := cmd.Bool("gzip") || strings.HasSuffix(file, ".gz")
+
+	slog.DebugContext(ctx, "exporting dead letter messages", "namespace", namespace, "entity", entity.String(), "file", file, "mode", cmd.String("mode"))
+
+	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		return fmt.Errorf("failed to get Service Bus client: %w", err)
+	}
+
+	// write to stdout or a new file, optionally gzip-compressed
+	var w io.Writer = os.Stdout
+	var f *os.File
+	var gz *gzip.Writer
+	if file != "-" {
+		f, err = os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to close export file: %w", closeErr)
+			}
+		}()
+		w = f
+	}
+	if compress {
+		gz = gzip.NewWriter(w)
+		defer func() {
+			if closeErr := gz.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to finish gzip stream: %w", closeErr)
+			}
+		}()
+		w = gz
+	}
+
+	exported, err := servicebus.ExportDeadLetterMessages(ctx, client, entity, w, &servicebus.ExportOptions{
+		Namespace:   namespace,
+		Mode:        servicebus.ExportMode(cmd.String("mode")),
+		MaxMessages: cmd.Int("max-messages"),
+		Redactor:    redactor,
+		// make received messages durable before they are completed
+		Sync: func() error {
+			if gz != nil {
+				if err := gz.Flush(); err != nil {
+					return err
+				}
+			}
+			if f != nil {
+				return f.Sync()
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export messages after %d messages: %w", exported, err)
+	}
+
+	// the summary goes to stderr when the export itself is written to stdout
+	out := os.Stdout
+	if file == "-" {
+		out = os.Stderr
+	}
+	result := exportResult{Entity: entity.String(), File: file, Exported: exported}
+	return output.print(out, &result, func(tw *tabwriter.Writer, wide bool) {
+		fmt.Fprintf(tw, "exported %d messages from %s to %s\n", result.Exported, result.Entity, result.File)
+	})
+}
+
+// JSON-serializable result of an export
+type exportResult struct {
+	Entity   string `json:"entity"`
+	File     string `json:"file"`
+	Exported int    `json:"exported"`
+}
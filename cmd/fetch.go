@@ -3,74 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"net/url"
 
-	"dlqt/internal/msal"
+	"dlqt/client"
 
 	"github.com/urfave/cli/v3"
 )
 
 func fetch(ctx context.Context, cmd *cli.Command) error {
-	// set configs
-	msalConfig := msal.MSALConfig{
-		TenantID:  cmd.String("cmd-tenant-id"),
-		ClientID:  cmd.String("cmd-client-id"),
-		Scope:     "api://" + cmd.String("api-client-id") + "/dlq.read",
-		CacheFile: "msal_cache.json",
-	}
-	apiConfig := msal.APIConfig{
-		APIEndpoint: cmd.String("api-url") + "/fetch",
-	}
-
-	// add URL query parameters
-	params := url.Values{}
-	params.Add("namespace", cmd.String("namespace"))
-	params.Add("queue", cmd.String("queue"))
-	fullURL := apiConfig.APIEndpoint + "?" + params.Encode()
-
-	// get JWT
-	token, err := msal.GetToken(ctx, &msalConfig)
+	entity, err := apiEntityFromFlags(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+		return err
 	}
-	log.Printf("token: %s\n", token)
 
-	// create request and auth header
-	req, err := http.NewRequest("GET", fullURL, nil)
+	decoders, err := newDecoders(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
 
-	// execute request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	apiClient, err := newAPIClient(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
-
-	// check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read error response body: %w", err)
-		}
-		return fmt.Errorf("failed to fetch data: %s", string(body))
-	}
-
-	// read response body
-	body, err := io.ReadAll(resp.Body)
+	message, err := apiClient.Fetch(ctx, cmd.String("namespace"), entity, &client.BodyOptions{MaxBodySize: cmd.Int("max-body-size")})
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("failed to fetch message: %w", err)
 	}
+	messages := fromAPIMessages([]*client.DeadLetterMessage{message})
+	decodeMessages(decoders, cmd.String("decoder"), messages)
 
-	log.Printf("response body: %s", string(body))
-
-	// TODO: handle response body
-
-	return nil
+	return printMessage(messages[0])
 }
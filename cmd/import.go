@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+func importMessages(ctx context.Context, cmd *cli.Command) error {
+	namespace := cmd.String("namespace")
+	entity := servicebus.Entity{
+		Queue: cmd.String("queue"),
+		Topic: cmd.String("topic"),
+	}
+	if err := entity.ValidateTarget(); err != nil {
+		return err
+	}
+	file := cmd.String("file")
+
+	slog.DebugContext(ctx, "importing messages", "namespace", namespace, "entity", entity.String(), "file", file, "offset", cmd.Int("offset"))
+
+	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
+	if err != nil {
+		return fmt.Errorf("failed to get Service Bus client: %w", err)
+	}
+
+	// read from stdin or a file, gzip-compressed input is detected by its magic bytes
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	offset, err := servicebus.ImportMessages(ctx, client, entity, r, &servicebus.ImportOptions{
+		RatePerSecond: cmd.Float64("rate"),
+		Offset:        cmd.Int("offset"),
+		AllowRedacted: cmd.Bool("allow-redacted"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import messages, resume with --offset %d: %w", offset, err)
+	}
+
+	result := importResult{Entity: entity.String(), File: file, Imported: offset - cmd.Int("offset"), Offset: offset}
+	return output.print(os.Stdout, &result, func(tw *tabwriter.Writer, wide bool) {
+		fmt.Fprintf(tw, "imported %d messages from %s to %s, next offset %d\n", result.Imported, result.File, result.Entity, result.Offset)
+	})
+}
+
+// JSON-serializable result of an import, offset is where a later import would resume
+type importResult struct {
+	Entity   string `json:"entity"`
+	File     string `json:"file"`
+	Imported int    `json:"imported"`
+	Offset   int    `json:"offset"`
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+
+	"dlqt/internal/logging"
+	"dlqt/internal/redact"
+
+	"github.com/urfave/cli/v3"
+)
+
+// redactor masks sensitive values in printed messages, exports and logs, nil when no rules are configured
+var redactor *redact.Redactor
+
+// setupLogging applies the --verbose, --quiet and --log-format flags, info level hides the per-message logs.
+// The --redaction-file rules are loaded here as they also apply to logs.
+func setupLogging(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if cmd.Bool("verbose") && cmd.Bool("quiet") {
+		return ctx, errors.New("--verbose and --quiet are mutually exclusive")
+	}
+	format, err := logging.ParseFormat(cmd.String("log-format"))
+	if err != nil {
+		return ctx, err
+	}
+	redactor, err = redact.Load(cmd.String("redaction-file"))
+	if err != nil {
+		return ctx, err
+	}
+	options := &logging.Options{Format: format, Level: slog.LevelInfo, Redactor: redactor}
+	if cmd.Bool("verbose") {
+		options.Level = slog.LevelDebug
+	} else if cmd.Bool("quiet") {
+		options.Level = slog.LevelWarn
+	}
+	logging.Setup(os.Stderr, options)
+	return ctx, nil
+}
@@ -3,31 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
+	"dlqt/internal/logging"
+	"dlqt/internal/servicebus"
+	"dlqt/internal/telemetry"
+
 	"github.com/joho/godotenv"
 	"github.com/urfave/cli/v3"
 )
 
-type timestampWriter struct{}
-
-func (w *timestampWriter) Write(p []byte) (n int, err error) {
-	timestamp := time.Now().UTC().Format(time.RFC3339)
-	message := fmt.Sprintf("%s %s", timestamp, string(p))
-	return os.Stderr.Write([]byte(message))
-}
-
 func main() {
-	// use RFC3339 timestamp for log messages
-	log.SetFlags(0)
-	log.SetOutput(&timestampWriter{})
+	// log text at info level until the flags are parsed
+	logging.Setup(os.Stderr, nil)
 
 	// load env vars from .env file
 	err := godotenv.Load()
 	if err != nil && !os.IsNotExist(err) {
-		log.Fatal(err)
+		fatal(err)
 	}
 
 	cmd := &cli.Command{
@@ -36,7 +31,34 @@ func main() {
 		Usage:                  "CLI tool for managing & interacting with Azure Service Bus Dead Letter Queues",
 		EnableShellCompletion:  true,
 		UseShortOptionHandling: true,
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			var err error
+			if output, err = parseOutput(cmd.String("output")); err != nil {
+				return ctx, err
+			}
+			return setupLogging(ctx, cmd)
+		},
 		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "verbose",
+				Usage: "log debug details, including every message processed",
+			},
+			&cli.BoolFlag{
+				Name:  "quiet",
+				Usage: "only log warnings and errors",
+			},
+			&cli.StringFlag{
+				Name:    "log-format",
+				Usage:   "the log format, text or json",
+				Value:   logging.FormatText,
+				Sources: cli.EnvVars("DLQT_LOG_FORMAT"),
+			},
+			outputFlag,
+			&cli.StringFlag{
+				Name:    "redaction-file",
+				Usage:   "a JSON file of redaction rules masking sensitive values in printed messages, exports and logs",
+				Sources: cli.EnvVars("DLQT_REDACTION_FILE"),
+			},
 			&cli.StringFlag{
 				Name:     "namespace",
 				Aliases:  []string{"n"},
@@ -47,9 +69,22 @@ func main() {
 			&cli.StringFlag{
 				Name:     "queue",
 				Aliases:  []string{"q"},
-				Usage:    "the Service Bus queue name",
+				Usage:    "the Service Bus queue name, or use --topic and --subscription",
 				Sources:  cli.EnvVars("AZURE_SERVICEBUS_QUEUE"),
-				Required: true,
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "topic",
+				Aliases:  []string{"t"},
+				Usage:    "the Service Bus topic name, used with --subscription",
+				Sources:  cli.EnvVars("AZURE_SERVICEBUS_TOPIC"),
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "subscription",
+				Usage:    "the Service Bus topic subscription name, used with --topic",
+				Sources:  cli.EnvVars("AZURE_SERVICEBUS_SUBSCRIPTION"),
+				Required: false,
 			},
 			&cli.StringFlag{
 				Name:     "api-url",
@@ -113,6 +148,18 @@ func main() {
 						Usage:    "do not dead-letter messages",
 						Required: false,
 					},
+					&cli.IntFlag{
+						Name:     "sessions",
+						Usage:    "spread messages across this many session IDs, session-enabled entities use at least one",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v < 0 {
+								return fmt.Errorf("sessions must not be negative, got %d", v)
+							}
+							return nil
+						},
+					},
 				},
 			},
 			// purge
@@ -144,6 +191,91 @@ func main() {
 					},
 				},
 			},
+			// export
+			{
+				Name:  "export",
+				Usage: "Export the dead-letter queue to an NDJSON file",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return exportMessages(ctx, cmd)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "the NDJSON file to create, - writes to stdout",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:     "gzip",
+						Usage:    "gzip-compress the output, implied by a .gz file extension",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "mode",
+						Usage:    "peek leaves messages in the dead-letter queue, receive completes them once written",
+						Value:    string(servicebus.ExportModePeek),
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v string) error {
+							if v != string(servicebus.ExportModePeek) && v != string(servicebus.ExportModeReceive) {
+								return fmt.Errorf("mode must be peek or receive, got %s", v)
+							}
+							return nil
+						},
+					},
+					&cli.IntFlag{
+						Name:     "max-messages",
+						Aliases:  []string{"m"},
+						Usage:    "the maximum number of messages to export, 0 exports the whole dead-letter queue",
+						Value:    0,
+						Required: false,
+					},
+				},
+			},
+			// import
+			{
+				Name:  "import",
+				Usage: "Send messages from an exported NDJSON file to the queue or topic",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return importMessages(ctx, cmd)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "the NDJSON file to read, optionally gzip-compressed, - reads from stdin",
+						Required: true,
+					},
+					&cli.Float64Flag{
+						Name:     "rate",
+						Usage:    "the maximum number of messages to send per second, 0 means no limit",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v float64) error {
+							if v < 0 {
+								return fmt.Errorf("rate must not be negative, got %v", v)
+							}
+							return nil
+						},
+					},
+					&cli.IntFlag{
+						Name:     "offset",
+						Usage:    "the number of records to skip, to resume an earlier import",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v < 0 {
+								return fmt.Errorf("offset must not be negative, got %d", v)
+							}
+							return nil
+						},
+					},
+					&cli.BoolFlag{
+						Name:     "allow-redacted",
+						Usage:    "send records of a redacted export, with their masked bodies and properties",
+						Required: false,
+					},
+				},
+			},
 			// fetch
 			{
 				Name:  "fetch",
@@ -151,26 +283,233 @@ func main() {
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					return fetch(ctx, cmd)
 				},
+				Flags: append([]cli.Flag{
+					&cli.IntFlag{
+						Name:     "max-body-size",
+						Usage:    "truncate message bodies to this many bytes, 0 means no limit",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v < 0 {
+								return fmt.Errorf("max-body-size must not be negative, got %d", v)
+							}
+							return nil
+						},
+					},
+				}, decoderFlags()...),
+			},
+			// peek
+			{
+				Name:  "peek",
+				Usage: "Peek messages from the dead letter queue without locking them (API required)",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return peek(ctx, cmd)
+				},
+				Flags: append([]cli.Flag{
+					&cli.Int64Flag{
+						Name:     "from",
+						Usage:    "the sequence number to start peeking from",
+						Value:    0,
+						Required: false,
+					},
+					&cli.IntFlag{
+						Name:     "page-size",
+						Usage:    "the number of messages to request per page",
+						Value:    50,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v <= 0 || v > 250 {
+								return fmt.Errorf("page-size must be between 1 and 250, got %d", v)
+							}
+							return nil
+						},
+					},
+					&cli.IntFlag{
+						Name:     "max-messages",
+						Aliases:  []string{"m"},
+						Usage:    "the maximum number of messages to peek, 0 peeks the whole dead letter queue",
+						Value:    0,
+						Required: false,
+					},
+					&cli.BoolFlag{
+						Name:     "group-by-session",
+						Usage:    "group each page of peeked messages by session ID, json and yaml print session groups",
+						Required: false,
+					},
+					&cli.IntFlag{
+						Name:     "max-body-size",
+						Usage:    "truncate message bodies to this many bytes, 0 means no limit",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v < 0 {
+								return fmt.Errorf("max-body-size must not be negative, got %d", v)
+							}
+							return nil
+						},
+					},
+				}, decoderFlags()...),
+			},
+			// stats
+			{
+				Name:  "stats",
+				Usage: "Show dead letter counts and the most common reasons, errors and subjects (API required)",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return stats(ctx, cmd)
+				},
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:     "max-messages",
+						Aliases:  []string{"m"},
+						Usage:    "the maximum number of dead letter messages to scan, defaults to the API's limit",
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v <= 0 {
+								return fmt.Errorf("max-messages must be positive, got %d", v)
+							}
+							return nil
+						},
+					},
+					&cli.DurationFlag{
+						Name:     "bucket",
+						Usage:    "the enqueued-time histogram bucket size",
+						Value:    time.Hour,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v time.Duration) error {
+							if v < time.Minute {
+								return fmt.Errorf("bucket must be at least 1m, got %s", v)
+							}
+							return nil
+						},
+					},
+					&cli.IntFlag{
+						Name:     "top",
+						Usage:    "the number of most common values to show per table, 0 shows all",
+						Value:    10,
+						Required: false,
+					},
+				},
 			},
 			// retrigger
 			{
 				Name:  "retrigger",
-				Usage: "Retrigger one message from the dead letter queue (API required)",
+				Usage: "Retrigger one or many messages from the dead letter queue (API required)",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					return retrigger(ctx, cmd)
 				},
+				MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
+					{
+						Required: true,
+						Flags: [][]cli.Flag{
+							{
+								&cli.StringFlag{
+									Name:     "message-id",
+									Usage:    "the message ID to retrigger",
+									Required: false,
+								},
+							},
+							{
+								&cli.Int64Flag{
+									Name:     "sequence-number",
+									Aliases:  []string{"s"},
+									Usage:    "the sequence number of the message to retrigger",
+									Required: false,
+								},
+							},
+							{
+								&cli.BoolFlag{
+									Name:     "all",
+									Usage:    "retrigger every message matching the filter flags",
+									Required: false,
+								},
+							},
+						},
+					},
+				},
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "message-id",
-						Usage:    "the message ID to retrigger",
-						Required: true,
+						Name:     "reason",
+						Usage:    "only retrigger messages with this dead-letter reason (requires --all)",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "error-description",
+						Usage:    "only retrigger messages whose dead-letter error description contains this text (requires --all)",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "since",
+						Usage:    "only retrigger messages enqueued since this RFC3339 time or duration ago, e.g. 2h (requires --all)",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "until",
+						Usage:    "only retrigger messages enqueued before this RFC3339 time or duration ago (requires --all)",
+						Required: false,
+					},
+					&cli.StringFlag{
+						Name:     "subject",
+						Usage:    "only retrigger messages with this subject (requires --all)",
+						Required: false,
+					},
+					&cli.StringSliceFlag{
+						Name:     "property",
+						Usage:    "only retrigger messages with this application property, as key=value, repeatable (requires --all)",
+						Required: false,
+					},
+					&cli.IntFlag{
+						Name:     "limit",
+						Usage:    "the maximum number of messages to retrigger, 0 means no limit (requires --all)",
+						Value:    0,
+						Required: false,
+						Action: func(ctx context.Context, cmd *cli.Command, v int) error {
+							if v < 0 {
+								return fmt.Errorf("limit must not be negative, got %d", v)
+							}
+							return nil
+						},
+					},
+					&cli.BoolFlag{
+						Name:     "target-subscription",
+						Usage:    "mark messages re-published to the topic as meant only for the source subscription",
+						Required: false,
+					},
+					&cli.StringSliceFlag{
+						Name:     "annotation",
+						Usage:    "set this application property on the retriggered messages, as key=value, repeatable",
+						Required: false,
+					},
+					&cli.BoolFlag{
+						Name:     "no-annotations",
+						Usage:    "don't stamp the dlqt-* properties recording the retrigger onto the messages",
+						Required: false,
+					},
+					&cli.BoolFlag{
+						Name:     "dry-run",
+						Usage:    "report the messages that would be retriggered without moving them (requires --all)",
+						Required: false,
 					},
 				},
 			},
 		},
 	}
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
-		log.Fatal(err)
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), cmd.Name, cmd.Version)
+	if err != nil {
+		fatal(err)
 	}
+	traceCommands(cmd)
+	err = cmd.Run(context.Background(), os.Args)
+	// flush spans before exiting
+	if err := shutdownTelemetry(context.Background()); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"dlqt/internal/servicebus"
+)
+
+// messageList prints as a JSON or YAML array, and runs output templates once per message
+type messageList []*servicebus.DeadLetterMessage
+
+func (l messageList) items() []any {
+	items := make([]any, len(l))
+	for i, message := range l {
+		items[i] = message
+	}
+	return items
+}
+
+// bodyPreviewLength is the number of characters of the body shown by the wide table
+const bodyPreviewLength = 40
+
+// sessionGroup is the messages of one session, the session is empty for messages without one
+type sessionGroup struct {
+	Session  string                          `json:"session"`
+	Messages []*servicebus.DeadLetterMessage `json:"messages"`
+}
+
+// sessionGroupList prints as a JSON or YAML array of groups, and runs output templates once per message
+type sessionGroupList []sessionGroup
+
+func (l sessionGroupList) items() []any {
+	var items []any
+	for _, group := range l {
+		items = append(items, messageList(group.Messages).items()...)
+	}
+	return items
+}
+
+// groupBySession groups messages by session ID, in order of each session's first message
+func groupBySession(messages []*servicebus.DeadLetterMessage) sessionGroupList {
+	var groups sessionGroupList
+	index := map[string]int{}
+	for _, message := range messages {
+		sessionID := ""
+		if message.SessionID != nil {
+			sessionID = *message.SessionID
+		}
+		i, ok := index[sessionID]
+		if !ok {
+			i = len(groups)
+			index[sessionID] = i
+			groups = append(groups, sessionGroup{Session: sessionID})
+		}
+		groups[i].Messages = append(groups[i].Messages, message)
+	}
+	return groups
+}
+
+// messageTable selects the columns of the message table, pages after the first skip the header
+type messageTable struct {
+	first    bool
+	sessions bool
+}
+
+// printMessage redacts and prints a single message, as an object rather than a list in JSON and YAML
+func printMessage(message *servicebus.DeadLetterMessage) error {
+	messages := []*servicebus.DeadLetterMessage{message}
+	return printMessageTable(message, messages, messageTable{first: true, sessions: hasSessions(messages)})
+}
+
+// printMessages redacts the messages and prints them as a list
+func printMessages(messages []*servicebus.DeadLetterMessage) error {
+	return printMessageTable(messageList(messages), messages, messageTable{first: true, sessions: hasSessions(messages)})
+}
+
+func hasSessions(messages []*servicebus.DeadLetterMessage) bool {
+	for _, message := range messages {
+		if message.SessionID != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// printMessageTable prints value, the wide table adds the subject, error description and a body preview
+func printMessageTable(value any, messages []*servicebus.DeadLetterMessage, table messageTable) error {
+	for _, message := range messages {
+		message.Redact(redactor)
+	}
+
+	return output.printPage(os.Stdout, value, table.first, func(tw *tabwriter.Writer, wide bool) {
+		columns := []string{"SEQUENCE", "MESSAGE ID", "ENQUEUED", "DELIVERIES", "REASON"}
+		if table.sessions {
+			columns = append(columns, "SESSION")
+		}
+		if wide {
+			columns = append(columns, "SUBJECT", "ERROR DESCRIPTION", "CONTENT TYPE", "SIZE", "BODY")
+		}
+		if table.first {
+			fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		}
+
+		for _, message := range messages {
+			row := []string{
+				formatInt64(message.SequenceNumber),
+				message.MessageID,
+				formatTime(message.EnqueuedTime),
+				strconv.FormatUint(uint64(message.DeliveryCount), 10),
+				formatString(message.DeadLetterReason),
+			}
+			if table.sessions {
+				row = append(row, formatString(message.SessionID))
+			}
+			if wide {
+				row = append(row,
+					formatString(message.Subject),
+					formatString(message.DeadLetterErrorDescription),
+					formatString(message.ContentType),
+					strconv.Itoa(message.BodySize),
+					bodyPreview(message),
+				)
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	})
+}
+
+// bodyPreview is the start of the decoded or raw body on one line, binary bodies are summarized
+func bodyPreview(message *servicebus.DeadLetterMessage) string {
+	body := message.DecodedBody
+	if body == "" {
+		if message.BodyEncoding == servicebus.BodyEncodingBase64 {
+			return "(binary)"
+		}
+		body = message.Body
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(body) > bodyPreviewLength {
+		body = string([]rune(body)[:bodyPreviewLength-1]) + "…"
+	}
+	return formatString(&body)
+}
+
+// table cells show "-" for missing values so columns stay aligned
+func formatString(value *string) string {
+	if value == nil || *value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(*value, "\t", " ")
+}
+
+func formatInt64(value *int64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+func formatTime(value *time.Time) string {
+	if value == nil {
+		return "-"
+	}
+	return value.UTC().Format(time.RFC3339)
+}
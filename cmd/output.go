@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// output formats of the --output flag, a Go template is given as template=...
+const (
+	outputTable    = "table"
+	outputWide     = "wide"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputTemplate = "template"
+)
+
+// output is set from the --output flag before any command runs
+var output = &printer{format: outputTable}
+
+// printer renders command results to stdout, logs stay on stderr so output can be piped into jq
+type printer struct {
+	format   string
+	template *template.Template
+}
+
+// parseOutput parses the --output flag
+func parseOutput(value string) (*printer, error) {
+	format, text, isTemplate := strings.Cut(value, "=")
+	switch {
+	case isTemplate && format == outputTemplate:
+		tmpl, err := template.New("output").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		return &printer{format: outputTemplate, template: tmpl}, nil
+	case !isTemplate && (format == outputTable || format == outputWide || format == outputJSON || format == outputYAML):
+		return &printer{format: format}, nil
+	default:
+		return nil, fmt.Errorf("invalid output '%s', expected table, wide, json, yaml or template=<go template>", value)
+	}
+}
+
+// outputFlag selects the printer, validated up front so a typo fails before any API call
+var outputFlag = &cli.StringFlag{
+	Name:    "output",
+	Aliases: []string{"o"},
+	Usage:   "the output format: table, wide, json, yaml or template=<go template>, templates run once per item of a list",
+	Value:   outputTable,
+	Sources: cli.EnvVars("DLQT_OUTPUT"),
+	Action: func(ctx context.Context, cmd *cli.Command, v string) error {
+		_, err := parseOutput(v)
+		return err
+	},
+}
+
+// print writes the value in the selected format, table and wide formats are rendered by the table func.
+// Templates run once per element when value is a slice.
+func (p *printer) print(w io.Writer, value any, table func(tw *tabwriter.Writer, wide bool)) error {
+	switch p.format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case outputYAML:
+		return writeYAML(w, value)
+	case outputTemplate:
+		return p.executeTemplate(w, value)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		table(tw, p.format == outputWide)
+		return tw.Flush()
+	}
+}
+
+// printPage prints one page of a paged result as it arrives, YAML pages are separate documents
+// and the table func only writes the header for the first page.
+func (p *printer) printPage(w io.Writer, value any, first bool, table func(tw *tabwriter.Writer, wide bool)) error {
+	if !first && p.format == outputYAML {
+		if _, err := fmt.Fprintln(w, "---"); err != nil {
+			return err
+		}
+	}
+	return p.print(w, value, table)
+}
+
+func (p *printer) executeTemplate(w io.Writer, value any) error {
+	items := []any{value}
+	if list, ok := value.(interface{ items() []any }); ok {
+		items = list.items()
+	}
+	for _, item := range items {
+		if err := p.template.Execute(w, item); err != nil {
+			return fmt.Errorf("failed to execute output template: %w", err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// writeYAML goes through JSON so fields keep their JSON names and order, JSON being valid YAML
+func writeYAML(w io.Writer, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style and quoting JSON parses with, so the YAML reads like hand-written YAML
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// toJSON is the json template function, e.g. {{json .ApplicationProperties}}
+func toJSON(value any) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
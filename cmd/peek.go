@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"dlqt/client"
+	"dlqt/internal/servicebus"
+
+	"github.com/urfave/cli/v3"
+)
+
+func peek(ctx context.Context, cmd *cli.Command) error {
+	entity, err := apiEntityFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	decoders, err := newDecoders(cmd)
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := newAPIClient(cmd)
+	if err != nil {
+		return err
+	}
+	from := cmd.Int64("from")
+	maxMessages := cmd.Int("max-messages")
+	peeked := 0
+
+	groupSessions := cmd.Bool("group-by-session")
+	table := messageTable{first: true, sessions: groupSessions}
+
+	// print each page as the API returns it, until it stops returning a cursor
+	for {
+		limit := cmd.Int("page-size")
+		if maxMessages > 0 {
+			limit = min(limit, maxMessages-peeked)
+		}
+
+		page, err := apiClient.Peek(ctx, cmd.String("namespace"), entity, &client.PeekOptions{
+			BodyOptions: client.BodyOptions{MaxBodySize: cmd.Int("max-body-size")},
+			From:        from,
+			Limit:       limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to peek messages: %w", err)
+		}
+		messages := fromAPIMessages(page.Messages)
+		decodeMessages(decoders, cmd.String("decoder"), messages)
+
+		if len(messages) > 0 {
+			// the first page decides the session column so later pages line up with its header
+			if table.first {
+				table.sessions = table.sessions || hasSessions(messages)
+			}
+			if err := printPeekPage(ctx, messages, groupSessions, table); err != nil {
+				return err
+			}
+			table.first = false
+		}
+		peeked += len(messages)
+
+		if page.Next == nil || (maxMessages > 0 && peeked >= maxMessages) {
+			break
+		}
+		from = *page.Next
+	}
+
+	slog.InfoContext(ctx, "peeked messages", "count", peeked)
+	if table.first {
+		return printMessages(nil)
+	}
+	return nil
+}
+
+// printPeekPage prints a page of peeked messages, grouped by session within the page when requested.
+// Grouped pages print as a list of session groups in JSON and YAML so the session key is kept.
+func printPeekPage(ctx context.Context, messages []*servicebus.DeadLetterMessage, groupSessions bool, table messageTable) error {
+	if !groupSessions {
+		return printMessageTable(messageList(messages), messages, table)
+	}
+
+	groups := groupBySession(messages)
+	var ordered []*servicebus.DeadLetterMessage
+	for _, group := range groups {
+		if group.Session == "" {
+			slog.InfoContext(ctx, "messages without session", "count", len(group.Messages))
+		} else {
+			slog.InfoContext(ctx, "session messages", "sessionID", group.Session, "count", len(group.Messages))
+		}
+		ordered = append(ordered, group.Messages...)
+	}
+	return printMessageTable(groups, ordered, table)
+}
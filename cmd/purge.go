@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"text/tabwriter"
 
 	"dlqt/internal/servicebus"
 
@@ -12,29 +14,45 @@ import (
 
 func purgeMessages(ctx context.Context, cmd *cli.Command) error {
 	namespace := cmd.String("namespace")
-	queue := cmd.String("queue")
+	entity, err := entityFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "purging entity", "namespace", namespace, "entity", entity.String())
 
-	log.Println("namespace:", namespace)
-	log.Println("queue:", queue)
+	entity = detectSession(ctx, namespace, entity)
 
 	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
 	if err != nil {
 		return fmt.Errorf("failed to get Service Bus client: %w", err)
 	}
 
+	result := purgeResult{Entity: entity.String()}
 	if !cmd.Bool("no-queue") {
-		log.Println("purging queue")
-		if err := servicebus.PurgeQueue(ctx, client, queue); err != nil {
-			return fmt.Errorf("failed to purge queue '%s': %w", queue, err)
+		slog.InfoContext(ctx, "purging queue")
+		if err := servicebus.PurgeQueue(ctx, client, entity); err != nil {
+			return fmt.Errorf("failed to purge %s: %w", entity, err)
 		}
+		result.Queue = true
 	}
 
 	if !cmd.Bool("no-dlq") {
-		log.Println("purging dead-letter queue")
-		if err := servicebus.PurgeDeadLetterQueue(ctx, client, queue); err != nil {
-			return fmt.Errorf("failed to purge dead-letter queue for '%s': %w", queue, err)
+		slog.InfoContext(ctx, "purging dead-letter queue")
+		if err := servicebus.PurgeDeadLetterQueue(ctx, client, entity); err != nil {
+			return fmt.Errorf("failed to purge dead-letter queue for %s: %w", entity, err)
 		}
+		result.DeadLetterQueue = true
 	}
 
-	return nil
+	return output.print(os.Stdout, &result, func(tw *tabwriter.Writer, wide bool) {
+		fmt.Fprintf(tw, "purged %s: queue %t, dead-letter queue %t\n", result.Entity, result.Queue, result.DeadLetterQueue)
+	})
+}
+
+// JSON-serializable result of a purge, recording which queues were purged
+type purgeResult struct {
+	Entity          string `json:"entity"`
+	Queue           bool   `json:"queue"`
+	DeadLetterQueue bool   `json:"deadLetterQueue"`
 }
@@ -1,88 +1,157 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
-	"dlqt/internal/msal"
+	"dlqt/client"
 
 	"github.com/urfave/cli/v3"
 )
 
 func retrigger(ctx context.Context, cmd *cli.Command) error {
-	// set configs
-	msalConfig := msal.MSALConfig{
-		TenantID:  cmd.String("cmd-tenant-id"),
-		ClientID:  cmd.String("cmd-client-id"),
-		Scope:     "api://" + cmd.String("api-client-id") + "/dlq.read",
-		CacheFile: "msal_cache.json",
-	}
-	apiConfig := msal.APIConfig{
-		APIEndpoint: cmd.String("api-url") + "/retrigger",
+	entity, err := apiEntityFromFlags(cmd)
+	if err != nil {
+		return err
 	}
 
-	// add URL query parameters
-	params := url.Values{}
-	params.Add("namespace", cmd.String("namespace"))
-	params.Add("queue", cmd.String("queue"))
-	fullURL := apiConfig.APIEndpoint + "?" + params.Encode()
-
-	// get JWT
-	token, err := msal.GetToken(ctx, &msalConfig)
+	request, bulkRequest, err := retriggerRequest(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+		return err
 	}
-	log.Printf("token: %s\n", token)
 
-	// prepare JSON payload
-	payload := map[string]string{
-		"message-id": cmd.String("message-id"),
-	}
-	jsonPayload, err := json.Marshal(payload)
+	apiClient, err := newAPIClient(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+		return err
 	}
 
-	// create request and auth header
-	req, err := http.NewRequest("PATCH", fullURL, bytes.NewBuffer(jsonPayload))
+	// a bulk retrigger reports the matched messages
+	if bulkRequest != nil {
+		result, err := apiClient.BulkRetrigger(ctx, cmd.String("namespace"), entity, bulkRequest)
+		if err != nil {
+			return fmt.Errorf("failed to retrigger messages: %w", err)
+		}
+		return printBulkRetriggerResult(result)
+	}
+	message, err := apiClient.Retrigger(ctx, cmd.String("namespace"), entity, request)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to retrigger message: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
+	result := retriggerResult{Message: message}
+	return output.print(os.Stdout, &result, func(tw *tabwriter.Writer, wide bool) {
+		fmt.Fprintln(tw, result.Message)
+	})
+}
+
+// JSON-serializable result of a single retrigger
+type retriggerResult struct {
+	Message string `json:"message"`
+}
+
+// printBulkRetriggerResult prints a summary line followed by the matched messages
+func printBulkRetriggerResult(result *client.BulkRetriggerResult) error {
+	return output.print(os.Stdout, result, func(tw *tabwriter.Writer, wide bool) {
+		if result.DryRun {
+			fmt.Fprintf(tw, "matched %d messages (dry run, none retriggered)\n", len(result.Matched))
+		} else {
+			fmt.Fprintf(tw, "retriggered %d of %d matched messages\n", result.Retriggered, len(result.Matched))
+		}
+		if len(result.Matched) == 0 {
+			return
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "SEQUENCE\tMESSAGE ID\tREASON")
+		for _, message := range result.Matched {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", message.SequenceNumber, message.MessageID, formatString(message.DeadLetterReason))
+		}
+	})
+}
+
+// bulk-only flags for retrigger --all
+var bulkRetriggerFlags = []string{"reason", "error-description", "since", "until", "subject", "property", "limit", "dry-run"}
 
-	// execute request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// retriggerRequest builds either a single or, with --all, a bulk retrigger request from the flags
+func retriggerRequest(cmd *cli.Command) (*client.RetriggerRequest, *client.BulkRetriggerRequest, error) {
+	annotations, err := parseKeyValues("annotation", cmd.StringSlice("annotation"))
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
-
-	// check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read error response body: %w", err)
+	if !cmd.Bool("all") {
+		for _, name := range bulkRetriggerFlags {
+			if cmd.IsSet(name) {
+				return nil, nil, fmt.Errorf("--%s requires --all", name)
+			}
+		}
+		request := &client.RetriggerRequest{
+			TargetSubscription: cmd.Bool("target-subscription"),
+			Annotations:        annotations,
+			DisableAnnotations: cmd.Bool("no-annotations"),
+		}
+		if cmd.IsSet("sequence-number") {
+			sequenceNumber := cmd.Int64("sequence-number")
+			request.SequenceNumber = &sequenceNumber
+		} else {
+			request.MessageID = cmd.String("message-id")
 		}
-		return fmt.Errorf("failed to fetch data: %s", string(body))
+		return request, nil, nil
 	}
 
-	// read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+	filter := &client.RetriggerFilter{
+		Reason:           cmd.String("reason"),
+		ErrorDescription: cmd.String("error-description"),
+		Subject:          cmd.String("subject"),
+	}
+	for name, field := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := cmd.String(name); v != "" {
+			t, err := parseTime(v, time.Now())
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid --%s: %w", name, err)
+			}
+			*field = &t
+		}
+	}
+	if filter.ApplicationProperties, err = parseKeyValues("property", cmd.StringSlice("property")); err != nil {
+		return nil, nil, err
 	}
 
-	log.Printf("response body: %s", string(body))
+	return nil, &client.BulkRetriggerRequest{
+		Filter:             filter,
+		Limit:              cmd.Int("limit"),
+		DryRun:             cmd.Bool("dry-run"),
+		TargetSubscription: cmd.Bool("target-subscription"),
+		Annotations:        annotations,
+		DisableAnnotations: cmd.Bool("no-annotations"),
+	}, nil
+}
 
-	// TODO: handle response body
+// parseKeyValues parses the key=value entries of a repeatable flag, nil when there are none
+func parseKeyValues(flag string, entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --%s '%s', expected key=value", flag, entry)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
 
-	return nil
+// parseTime accepts an RFC3339 timestamp or a duration relative to now, e.g. 2h
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 timestamp or duration, got '%s'", value)
+	}
+	return t, nil
 }
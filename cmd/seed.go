@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"text/tabwriter"
 
 	"dlqt/internal/servicebus"
 
@@ -12,12 +14,15 @@ import (
 
 func seedMessages(ctx context.Context, cmd *cli.Command) error {
 	namespace := cmd.String("namespace")
-	queue := cmd.String("queue")
+	entity, err := entityFromFlags(cmd)
+	if err != nil {
+		return err
+	}
 	numMessages := cmd.Int("num-messages")
 
-	log.Println("namespace:", namespace)
-	log.Println("queue:", queue)
-	log.Println("number of messages:", numMessages)
+	slog.DebugContext(ctx, "seeding entity", "namespace", namespace, "entity", entity.String(), "messages", numMessages, "sessions", cmd.Int("sessions"))
+
+	entity = detectSession(ctx, namespace, entity)
 
 	client, err := servicebus.GetClient(namespace + ".servicebus.windows.net")
 	if err != nil {
@@ -28,19 +33,30 @@ func seedMessages(ctx context.Context, cmd *cli.Command) error {
 	for i := range messages {
 		messages[i] = fmt.Sprintf("testMessage%d", i+1)
 	}
-	log.Printf("seeding %d messages", len(messages))
-	if err := servicebus.SendMessageBatch(ctx, client, queue, messages[:]); err != nil {
+	slog.InfoContext(ctx, "seeding messages", "count", len(messages))
+	if err := servicebus.SendMessageBatch(ctx, client, entity, messages[:], &servicebus.SendOptions{Sessions: cmd.Int("sessions")}); err != nil {
 		return fmt.Errorf("failed to send messages: %w", err)
 	}
 
+	result := seedResult{Entity: entity.String(), Seeded: len(messages)}
 	if !cmd.Bool("no-dlq") {
-		log.Println("moving messages to dead-letter queue")
-		if err := servicebus.DeadLetterMessages(ctx, client, queue, len(messages)); err != nil {
+		slog.InfoContext(ctx, "moving messages to dead-letter queue")
+		if err := servicebus.DeadLetterMessages(ctx, client, entity, len(messages)); err != nil {
 			return fmt.Errorf("failed to dead-letter messages: %w", err)
 		}
+		result.DeadLettered = len(messages)
 	} else {
-		log.Println("skipping dead-lettering messages")
+		slog.InfoContext(ctx, "skipping dead-lettering messages")
 	}
 
-	return nil
+	return output.print(os.Stdout, &result, func(tw *tabwriter.Writer, wide bool) {
+		fmt.Fprintf(tw, "seeded %d messages to %s, %d dead-lettered\n", result.Seeded, result.Entity, result.DeadLettered)
+	})
+}
+
+// JSON-serializable result of a seed
+type seedResult struct {
+	Entity       string `json:"entity"`
+	Seeded       int    `json:"seeded"`
+	DeadLettered int    `json:"deadLettered"`
 }
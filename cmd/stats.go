@@ -0,0 +1,115 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"dlqt/client"
+
+	"github.com/urfave/cli/v3"
+)
+
+func stats(ctx context.Context, cmd *cli.Command) error {
+	entity, err := apiEntityFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := newAPIClient(cmd)
+	if err != nil {
+		return err
+	}
+	options := &client.StatsOptions{BucketSize: cmd.Duration("bucket"), Top: cmd.Int("top")}
+	if cmd.IsSet("max-messages") {
+		options.MaxMessages = cmd.Int("max-messages")
+	}
+	stats, err := apiClient.Stats(ctx, cmd.String("namespace"), entity, options)
+	if err != nil {
+		return fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	stats.ByErrorDescription = redactCounts(stats.ByErrorDescription)
+	return output.print(os.Stdout, stats, func(tw *tabwriter.Writer, wide bool) {
+		printStats(tw, stats)
+	})
+}
+
+// printStats writes the stats as aligned tables
+func printStats(tw *tabwriter.Writer, stats *client.DeadLetterStats) {
+	if runtime := stats.Runtime; runtime != nil {
+		fmt.Fprintln(tw, "COUNT\tMESSAGES")
+		fmt.Fprintf(tw, "active\t%d\n", runtime.ActiveMessageCount)
+		fmt.Fprintf(tw, "dead-letter\t%d\n", runtime.DeadLetterMessageCount)
+		if runtime.ScheduledMessageCount != nil {
+			fmt.Fprintf(tw, "scheduled\t%d\n", *runtime.ScheduledMessageCount)
+		}
+		fmt.Fprintf(tw, "transfer\t%d\n", runtime.TransferMessageCount)
+		fmt.Fprintf(tw, "transfer dead-letter\t%d\n", runtime.TransferDeadLetterMessageCount)
+		fmt.Fprintf(tw, "total\t%d\n", runtime.TotalMessageCount)
+		if runtime.SizeInBytes != nil {
+			fmt.Fprintf(tw, "size (bytes)\t%d\n", *runtime.SizeInBytes)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	scanned := strconv.Itoa(stats.Scanned)
+	if stats.Truncated {
+		scanned += " (scan limit reached)"
+	}
+	fmt.Fprintf(tw, "scanned\t%s\n", scanned)
+	if stats.OldestEnqueuedTime != nil && stats.NewestEnqueuedTime != nil {
+		fmt.Fprintf(tw, "oldest\t%s\n", stats.OldestEnqueuedTime.Format(time.RFC3339))
+		fmt.Fprintf(tw, "newest\t%s\n", stats.NewestEnqueuedTime.Format(time.RFC3339))
+	}
+
+	for _, table := range []struct {
+		header string
+		counts []client.ValueCount
+	}{
+		{"REASON", stats.ByReason},
+		{"ERROR DESCRIPTION", stats.ByErrorDescription},
+		{"SUBJECT", stats.BySubject},
+	} {
+		fmt.Fprintf(tw, "\n%s\tMESSAGES\n", table.header)
+		for _, count := range table.counts {
+			value := count.Value
+			if value == "" {
+				value = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%d\n", value, count.Count)
+		}
+	}
+
+	fmt.Fprintf(tw, "\nENQUEUED (%s BUCKETS)\tMESSAGES\n", stats.BucketSize)
+	for _, bucket := range stats.ByEnqueuedTime {
+		fmt.Fprintf(tw, "%s\t%d\n", bucket.Start.Format(time.RFC3339), bucket.Count)
+	}
+}
+
+// redactCounts masks the values with the local redaction rules, merging rows that are masked alike
+func redactCounts(counts []client.ValueCount) []client.ValueCount {
+	if !redactor.Enabled() {
+		return counts
+	}
+	merged := make([]client.ValueCount, 0, len(counts))
+	index := map[string]int{}
+	for _, count := range counts {
+		value := redactor.Text(count.Value)
+		if i, ok := index[value]; ok {
+			merged[i].Count += count.Count
+			continue
+		}
+		index[value] = len(merged)
+		merged = append(merged, client.ValueCount{Value: value, Count: count.Count})
+	}
+	slices.SortStableFunc(merged, func(x, y client.ValueCount) int {
+		return cmp.Compare(y.Count, x.Count)
+	})
+	return merged
+}
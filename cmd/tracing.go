@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("dlqt/cmd")
+
+// traceCommands wraps every command action in a span named after the full command, e.g. "dlqt retrigger"
+func traceCommands(cmd *cli.Command) {
+	for _, sub := range cmd.Commands {
+		traceCommands(sub)
+	}
+	if cmd.Action == nil {
+		return
+	}
+	action := cmd.Action
+	cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+		ctx, span := tracer.Start(ctx, cmd.FullName())
+		defer span.End()
+		err := action(ctx, cmd)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
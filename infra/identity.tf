@@ -4,6 +4,10 @@ resource "random_uuid" "dlqt_api_scope_read_id" {}
 
 resource "random_uuid" "dlqt_api_scope_retrigger_id" {}
 
+resource "random_uuid" "dlqt_api_role_read_id" {}
+
+resource "random_uuid" "dlqt_api_role_retrigger_id" {}
+
 # TODO: how to expose the app ID URI? azapi? (did via portal)
 # TODO: how to add app ID URI to identifier URIs? (did via cli)
 # az ad app update --id 074c5ac1-4ab2-4a8a-b811-2d7b8c4e419f --identifier-uris api://074c5ac1-4ab2-4a8a-b811-2d7b8c4e419f
@@ -42,6 +46,25 @@ resource "azuread_application" "dlqt_api" {
     }
   }
 
+  # app roles for service principals calling with client credentials
+  app_role {
+    allowed_member_types = ["Application"]
+    description          = "Read DLQ Messages"
+    display_name         = "Read DLQ Messages"
+    enabled              = true
+    id                   = random_uuid.dlqt_api_role_read_id.result
+    value                = "dlq.read"
+  }
+
+  app_role {
+    allowed_member_types = ["Application"]
+    description          = "Retrigger DLQ Messages"
+    display_name         = "Retrigger DLQ Messages"
+    enabled              = true
+    id                   = random_uuid.dlqt_api_role_retrigger_id.result
+    value                = "dlq.retrigger"
+  }
+
   lifecycle {
     ignore_changes = [ identifier_uris ]
   }
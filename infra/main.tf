@@ -48,6 +48,45 @@ resource "azurerm_container_app" "api" {
       image  = "ghcr.io/emerconn/dlqt/api:latest"
       cpu    = 0.25
       memory = "0.5Gi"
+
+      env {
+        name  = "DLQT_API_TENANT_IDS"
+        value = data.azuread_client_config.current.tenant_id
+      }
+
+      env {
+        name  = "DLQT_API_AUDIENCES"
+        value = azuread_application.dlqt_api.client_id
+      }
+
+      env {
+        name  = "DLQT_API_ALLOWED_NAMESPACES"
+        value = azurerm_servicebus_namespace.this.name
+      }
+
+      env {
+        name  = "DLQT_API_AUDIT_STDOUT"
+        value = "true"
+      }
+
+      env {
+        name  = "DLQT_API_READINESS_NAMESPACES"
+        value = azurerm_servicebus_namespace.this.name
+      }
+
+      liveness_probe {
+        transport = "HTTP"
+        port      = 8080
+        path      = "/healthz"
+      }
+
+      # DLQT_API_DRAIN_DELAY (10s by default) must cover at least one interval
+      readiness_probe {
+        transport        = "HTTP"
+        port             = 8080
+        path             = "/readyz"
+        interval_seconds = 10
+      }
     }
   }
 
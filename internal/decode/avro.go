@@ -0,0 +1,52 @@
+package decode
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroDecoder renders Avro binary bodies as JSON using a schema file
+type AvroDecoder struct {
+	codec *goavro.Codec
+}
+
+// NewAvroDecoder loads an Avro schema file in JSON form
+func NewAvroDecoder(schemaFile string) (*AvroDecoder, error) {
+	schema, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avro schema: %w", err)
+	}
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+	}
+	return &AvroDecoder{codec: codec}, nil
+}
+
+func (d *AvroDecoder) Name() string { return "avro" }
+
+func (d *AvroDecoder) Match(mediaType string) bool {
+	switch mediaType {
+	case "avro/binary", "application/avro", "application/x-avro-binary", "application/vnd.apache.avro+binary":
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *AvroDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	native, remaining, err := d.codec.NativeFromBinary(body)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(remaining) > 0 {
+		return nil, "", fmt.Errorf("%d trailing bytes after avro datum", len(remaining))
+	}
+	decoded, err := d.codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, "application/json", nil
+}
@@ -0,0 +1,113 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// CloudEventsName is the name of the CloudEvents decoder
+	CloudEventsName = "cloudevents"
+
+	cloudEventsJSON = "application/cloudevents+json"
+	// the AMQP binding prefixes attributes with cloudEvents: or cloudEvents_
+	cloudEventsPrefix    = "cloudEvents:"
+	cloudEventsAltPrefix = "cloudEvents_"
+)
+
+// cloudEventsDecoder renders structured mode CloudEvents, decoding their data with the registry
+type cloudEventsDecoder struct {
+	registry *Registry
+}
+
+func (d *cloudEventsDecoder) Name() string { return CloudEventsName }
+
+func (d *cloudEventsDecoder) Match(mediaType string) bool {
+	return mediaType == cloudEventsJSON
+}
+
+func (d *cloudEventsDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	var event map[string]any
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, "", err
+	}
+
+	dataContentType, _ := event["datacontenttype"].(string)
+	if encoded, ok := event["data_base64"].(string); ok {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", err
+		}
+		delete(event, "data_base64")
+		d.registry.setData(event, data, dataContentType)
+	} else if data, ok := event["data"].(string); ok && dataContentType != "" {
+		// string data in a non-JSON content type, e.g. XML
+		d.registry.setData(event, []byte(data), dataContentType)
+	}
+
+	decoded, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, "application/json", nil
+}
+
+// isCloudEventsBinary reports whether the application properties carry a binary mode CloudEvent with either prefix
+func isCloudEventsBinary(properties map[string]any) bool {
+	if _, ok := properties[cloudEventsPrefix+"specversion"]; ok {
+		return true
+	}
+	_, ok := properties[cloudEventsAltPrefix+"specversion"]
+	return ok
+}
+
+// decodeCloudEventsBinary renders a binary mode CloudEvent, the attributes are application properties and the body is the data
+func (r *Registry) decodeCloudEventsBinary(body []byte, contentType string, properties map[string]any) (*Result, error) {
+	event := map[string]any{}
+	for key, value := range properties {
+		if attribute, ok := strings.CutPrefix(key, cloudEventsPrefix); ok {
+			event[attribute] = value
+		} else if attribute, ok := strings.CutPrefix(key, cloudEventsAltPrefix); ok {
+			event[attribute] = value
+		}
+	}
+	if _, ok := event["datacontenttype"]; !ok && contentType != "" {
+		event["datacontenttype"] = contentType
+	}
+
+	dataContentType, _ := event["datacontenttype"].(string)
+	inner := r.setData(event, body, dataContentType)
+
+	decoded, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Body:        decoded,
+		ContentType: "application/json",
+		Decoders:    append([]string{CloudEventsName}, inner...),
+	}, nil
+}
+
+// setData decodes event data into the event, as JSON when it decodes to JSON, otherwise as a string or data_base64
+func (r *Registry) setData(event map[string]any, data []byte, contentType string) []string {
+	result, err := r.Decode(data, contentType, nil, Auto)
+	if err != nil {
+		result = &Result{Body: data, ContentType: contentType}
+	}
+
+	trimmed := bytes.TrimSpace(result.Body)
+	switch {
+	case Sniff(trimmed) == "application/json" || Sniff(trimmed) == cloudEventsJSON:
+		event["data"] = json.RawMessage(trimmed)
+	case utf8.Valid(result.Body):
+		event["data"] = string(result.Body)
+	default:
+		delete(event, "data")
+		event["data_base64"] = base64.StdEncoding.EncodeToString(result.Body)
+	}
+	return result.Decoders
+}
@@ -0,0 +1,68 @@
+package decode
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxDecompressedSize guards against decompression bombs
+const maxDecompressedSize = 64 << 20
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gzipDecoder decompresses gzip bodies
+type gzipDecoder struct{}
+
+func (gzipDecoder) Name() string { return "gzip" }
+
+func (gzipDecoder) Match(mediaType string) bool {
+	return mediaType == "application/gzip" || mediaType == "application/x-gzip"
+}
+
+func (gzipDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	decompressed, err := readLimited(reader)
+	return decompressed, "", err
+}
+
+// deflateDecoder decompresses zlib-wrapped or raw deflate bodies
+type deflateDecoder struct{}
+
+func (deflateDecoder) Name() string { return "deflate" }
+
+func (deflateDecoder) Match(mediaType string) bool {
+	return mediaType == "application/deflate" || mediaType == "application/x-deflate" || mediaType == "application/zlib"
+}
+
+func (deflateDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	if reader, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+		defer reader.Close()
+		decompressed, err := readLimited(reader)
+		return decompressed, "", err
+	}
+
+	reader := flate.NewReader(bytes.NewReader(body))
+	defer reader.Close()
+	decompressed, err := readLimited(reader)
+	return decompressed, "", err
+}
+
+func readLimited(reader io.Reader) ([]byte, error) {
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", maxDecompressedSize)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,227 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+)
+
+const (
+	// DecoderProperty is the application property a producer can set to pick a decoder by name
+	DecoderProperty = "dlqt-decoder"
+	// Auto picks a decoder by the decoder property, then the content type, then by sniffing the body
+	Auto = "auto"
+	// None disables decoding
+	None = "none"
+
+	// maxChain bounds how many decoders are applied to one body, e.g. gzip then protobuf then json
+	maxChain = 4
+)
+
+// ErrNoDecoder is returned when no decoder matches the body
+var ErrNoDecoder = errors.New("no decoder matches the message")
+
+// Decoder renders a raw message body in a readable form
+type Decoder interface {
+	// Name identifies the decoder in flags, query parameters and the decoder property
+	Name() string
+	// Match reports whether the decoder handles a media type, without parameters
+	Match(mediaType string) bool
+	// Decode returns the decoded body and its content type, which is decoded further if another decoder matches it
+	Decode(body []byte, contentType string) ([]byte, string, error)
+}
+
+// Result is a decoded body and the chain of decoders applied to it
+type Result struct {
+	Body        []byte
+	ContentType string
+	Decoders    []string
+}
+
+// Registry picks decoders for message bodies
+type Registry struct {
+	decoders []Decoder
+}
+
+// NewRegistry creates a registry, decoders registered first win when several match a content type
+func NewRegistry(decoders ...Decoder) *Registry {
+	r := &Registry{}
+	for _, decoder := range decoders {
+		r.Register(decoder)
+	}
+	return r
+}
+
+// Register adds a decoder, replacing any decoder with the same name
+func (r *Registry) Register(decoder Decoder) {
+	for i, registered := range r.decoders {
+		if registered.Name() == decoder.Name() {
+			r.decoders[i] = decoder
+			return
+		}
+	}
+	r.decoders = append(r.decoders, decoder)
+}
+
+// Lookup returns the decoder registered under a name
+func (r *Registry) Lookup(name string) (Decoder, bool) {
+	for _, decoder := range r.decoders {
+		if decoder.Name() == name {
+			return decoder, true
+		}
+	}
+	return nil, false
+}
+
+// Names lists the registered decoders
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.decoders))
+	for _, decoder := range r.decoders {
+		names = append(names, decoder.Name())
+	}
+	return names
+}
+
+// match returns the first decoder for a content type
+func (r *Registry) match(contentType string) Decoder {
+	mediaType := MediaType(contentType)
+	if mediaType == "" {
+		return nil
+	}
+	for _, decoder := range r.decoders {
+		if decoder.Match(mediaType) {
+			return decoder
+		}
+	}
+	return nil
+}
+
+// Decode renders a message body, name forces the first decoder unless it is empty or Auto
+func (r *Registry) Decode(body []byte, contentType string, properties map[string]any, name string) (*Result, error) {
+	if name == None {
+		return nil, ErrNoDecoder
+	}
+
+	// CloudEvents in binary mode carry their attributes as application properties
+	if isCloudEventsBinary(properties) && (name == "" || name == Auto || name == CloudEventsName) {
+		return r.decodeCloudEventsBinary(body, contentType, properties)
+	}
+
+	if name == "" || name == Auto {
+		if property, ok := properties[DecoderProperty].(string); ok {
+			name = property
+		}
+	}
+
+	var first Decoder
+	if name != "" && name != Auto {
+		decoder, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown decoder '%s', expected one of %s", name, strings.Join(r.Names(), ", "))
+		}
+		first = decoder
+	} else {
+		if contentType == "" {
+			contentType = Sniff(body)
+		}
+		first = r.match(contentType)
+		if first == nil {
+			return nil, ErrNoDecoder
+		}
+	}
+
+	return r.chain(first, body, contentType)
+}
+
+// chain applies decoders until none matches the decoded content type, or one would repeat
+func (r *Registry) chain(decoder Decoder, body []byte, contentType string) (*Result, error) {
+	result := &Result{}
+	for decoder != nil && len(result.Decoders) < maxChain && !slices.Contains(result.Decoders, decoder.Name()) {
+		decoded, decodedType, err := decoder.Decode(body, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("%s decoder: %w", decoder.Name(), err)
+		}
+		result.Decoders = append(result.Decoders, decoder.Name())
+		body, contentType = decoded, decodedType
+		if contentType == "" {
+			contentType = Sniff(body)
+		}
+		decoder = r.match(contentType)
+	}
+
+	result.Body = body
+	result.ContentType = contentType
+	return result, nil
+}
+
+// MediaType returns the lowercased media type of a content type, without parameters
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return mediaType
+}
+
+// Sniff guesses the content type of a body without one
+func Sniff(body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case bytes.HasPrefix(body, gzipMagic):
+		return "application/gzip"
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed):
+		var envelope struct {
+			SpecVersion string `json:"specversion"`
+		}
+		if json.Unmarshal(trimmed, &envelope) == nil && envelope.SpecVersion != "" {
+			return cloudEventsJSON
+		}
+		return "application/json"
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "application/xml"
+	default:
+		return ""
+	}
+}
+
+// NewDefaultRegistry creates a registry with the decoders that need no configuration
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry(gzipDecoder{}, deflateDecoder{})
+	r.Register(&cloudEventsDecoder{registry: r})
+	r.Register(jsonDecoder{})
+	r.Register(xmlDecoder{})
+	return r
+}
+
+// Config names the schema files for the decoders that need them, empty files leave those decoders out
+type Config struct {
+	// ProtobufDescriptorSet is a FileDescriptorSet file, ProtobufMessage the default fully-qualified message type
+	ProtobufDescriptorSet string
+	ProtobufMessage       string
+	// AvroSchema is an Avro schema file in JSON form
+	AvroSchema string
+}
+
+// NewConfiguredRegistry creates a default registry plus the protobuf and avro decoders the config has files for
+func NewConfiguredRegistry(config Config) (*Registry, error) {
+	r := NewDefaultRegistry()
+	if config.ProtobufDescriptorSet != "" {
+		decoder, err := NewProtobufDecoder(config.ProtobufDescriptorSet, config.ProtobufMessage)
+		if err != nil {
+			return nil, err
+		}
+		r.Register(decoder)
+	}
+	if config.AvroSchema != "" {
+		decoder, err := NewAvroDecoder(config.AvroSchema)
+		if err != nil {
+			return nil, err
+		}
+		r.Register(decoder)
+	}
+	return r, nil
+}
@@ -0,0 +1,273 @@
+package decode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func gzipBody(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("failed to gzip body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to gzip body: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zlibBody(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("failed to deflate body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to deflate body: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRegistryDecode(t *testing.T) {
+	registry := NewDefaultRegistry()
+
+	tests := []struct {
+		name         string
+		body         []byte
+		contentType  string
+		properties   map[string]any
+		decoder      string
+		wantBody     string
+		wantDecoders []string
+		wantErr      error
+	}{
+		{
+			name:         "JSON",
+			body:         []byte(`{"a":1}`),
+			contentType:  "application/json",
+			wantBody:     "{\n  \"a\": 1\n}",
+			wantDecoders: []string{"json"},
+		},
+		{
+			name:         "SniffedJSON",
+			body:         []byte(`[1]`),
+			wantBody:     "[\n  1\n]",
+			wantDecoders: []string{"json"},
+		},
+		{
+			name:         "XML",
+			body:         []byte(`<a><b>1</b></a>`),
+			contentType:  "application/xml",
+			wantBody:     "<a>\n  <b>1</b>\n</a>",
+			wantDecoders: []string{"xml"},
+		},
+		{
+			name:         "GzipJSON",
+			body:         gzipBody(t, []byte(`{"a":1}`)),
+			contentType:  "application/gzip",
+			wantBody:     "{\n  \"a\": 1\n}",
+			wantDecoders: []string{"gzip", "json"},
+		},
+		{
+			name:         "SniffedGzip",
+			body:         gzipBody(t, []byte("text")),
+			wantBody:     "text",
+			wantDecoders: []string{"gzip"},
+		},
+		{
+			name:         "DeflateXML",
+			body:         zlibBody(t, []byte(`<a/>`)),
+			properties:   map[string]any{DecoderProperty: "deflate"},
+			wantBody:     "<a></a>",
+			wantDecoders: []string{"deflate", "xml"},
+		},
+		{
+			name:         "ForcedDecoder",
+			body:         []byte(`{"a":1}`),
+			contentType:  "text/plain",
+			decoder:      "json",
+			wantBody:     "{\n  \"a\": 1\n}",
+			wantDecoders: []string{"json"},
+		},
+		{
+			name:        "PlainText",
+			body:        []byte("text"),
+			contentType: "text/plain",
+			wantErr:     ErrNoDecoder,
+		},
+		{
+			name:    "None",
+			body:    []byte(`{"a":1}`),
+			decoder: None,
+			wantErr: ErrNoDecoder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := registry.Decode(tt.body, tt.contentType, tt.properties, tt.decoder)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if string(result.Body) != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, result.Body)
+			}
+			if !slices.Equal(result.Decoders, tt.wantDecoders) {
+				t.Errorf("expected decoders %v, got %v", tt.wantDecoders, result.Decoders)
+			}
+		})
+	}
+
+	t.Run("UnknownDecoder", func(t *testing.T) {
+		if _, err := registry.Decode([]byte("x"), "", nil, "missing"); err == nil || errors.Is(err, ErrNoDecoder) {
+			t.Errorf("expected unknown decoder error, got %v", err)
+		}
+	})
+}
+
+func TestCloudEvents(t *testing.T) {
+	registry := NewDefaultRegistry()
+
+	t.Run("Structured", func(t *testing.T) {
+		data := base64.StdEncoding.EncodeToString(gzipBody(t, []byte(`{"order":1}`)))
+		body := []byte(`{"specversion":"1.0","type":"order.created","datacontenttype":"application/gzip","data_base64":"` + data + `"}`)
+
+		result, err := registry.Decode(body, "", nil, Auto)
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if result.Decoders[0] != CloudEventsName {
+			t.Errorf("expected cloudevents decoder first, got %v", result.Decoders)
+		}
+		if !strings.Contains(string(result.Body), `"order": 1`) || strings.Contains(string(result.Body), "data_base64") {
+			t.Errorf("expected decoded data, got %s", result.Body)
+		}
+	})
+
+	for _, prefix := range []string{"cloudEvents:", "cloudEvents_"} {
+		t.Run("Binary"+prefix, func(t *testing.T) {
+			properties := map[string]any{
+				prefix + "specversion": "1.0",
+				prefix + "type":        "order.created",
+			}
+
+			result, err := registry.Decode([]byte(`{"order":1}`), "application/json", properties, Auto)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if !slices.Equal(result.Decoders, []string{CloudEventsName, "json"}) {
+				t.Errorf("expected decoders [cloudevents json], got %v", result.Decoders)
+			}
+			for _, want := range []string{`"type": "order.created"`, `"datacontenttype": "application/json"`, `"order": 1`} {
+				if !strings.Contains(string(result.Body), want) {
+					t.Errorf("expected %s in %s", want, result.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestAvroDecoder(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "order.avsc")
+	if err := os.WriteFile(schema, []byte(`{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`), 0600); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+	registry, err := NewConfiguredRegistry(Config{AvroSchema: schema})
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	// zig-zag encoded long 21
+	result, err := registry.Decode([]byte{0x2a}, "avro/binary", nil, Auto)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if want := "{\n  \"id\": 21\n}"; string(result.Body) != want {
+		t.Errorf("expected body %q, got %q", want, result.Body)
+	}
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("order.proto"),
+		Package: proto.String("orders"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("id"),
+				JsonName: proto.String("id"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}},
+		}},
+	}
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	if err != nil {
+		t.Fatalf("failed to marshal descriptor set: %v", err)
+	}
+	descriptorSet := filepath.Join(t.TempDir(), "orders.pb")
+	if err := os.WriteFile(descriptorSet, set, 0600); err != nil {
+		t.Fatalf("failed to write descriptor set: %v", err)
+	}
+
+	// encode a message with the same descriptor
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	message := dynamicpb.NewMessage(fd.Messages().ByName("Order"))
+	message.Set(fd.Messages().ByName("Order").Fields().ByName("id"), protoreflect.ValueOfString("o-1"))
+	body, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	registry, err := NewConfiguredRegistry(Config{ProtobufDescriptorSet: descriptorSet})
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	t.Run("ContentTypeMessageType", func(t *testing.T) {
+		result, err := registry.Decode(body, "application/x-protobuf; messageType=orders.Order", nil, Auto)
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if want := "{\n  \"id\": \"o-1\"\n}"; string(result.Body) != want {
+			t.Errorf("expected body %q, got %q", want, result.Body)
+		}
+		if !slices.Equal(result.Decoders, []string{"protobuf", "json"}) {
+			t.Errorf("expected decoders [protobuf json], got %v", result.Decoders)
+		}
+	})
+
+	t.Run("NoMessageType", func(t *testing.T) {
+		if _, err := registry.Decode(body, "application/x-protobuf", nil, Auto); err == nil {
+			t.Error("expected error without a message type")
+		}
+	})
+}
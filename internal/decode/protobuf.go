@@ -0,0 +1,89 @@
+package decode
+
+import (
+	"fmt"
+	"mime"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// content type parameters naming the message type, e.g. application/x-protobuf; messageType=orders.v1.Order
+var protobufTypeParams = []string{"messagetype", "proto", "type"}
+
+// ProtobufDecoder renders protobuf bodies as JSON using message types from a descriptor set
+type ProtobufDecoder struct {
+	files       *protoregistry.Files
+	messageType string
+}
+
+// NewProtobufDecoder loads a descriptor set file, as written by protoc --descriptor_set_out --include_imports,
+// messageType is the fully-qualified message used when the content type doesn't name one
+func NewProtobufDecoder(descriptorSetFile string, messageType string) (*ProtobufDecoder, error) {
+	data, err := os.ReadFile(descriptorSetFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors: %w", err)
+	}
+
+	return &ProtobufDecoder{files: files, messageType: messageType}, nil
+}
+
+func (d *ProtobufDecoder) Name() string { return "protobuf" }
+
+func (d *ProtobufDecoder) Match(mediaType string) bool {
+	switch mediaType {
+	case "application/protobuf", "application/x-protobuf", "application/vnd.google.protobuf", "application/x-google-protobuf":
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *ProtobufDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	messageType := d.messageType
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		for _, param := range protobufTypeParams {
+			if v, ok := params[param]; ok {
+				messageType = v
+				break
+			}
+		}
+	}
+	if messageType == "" {
+		return nil, "", fmt.Errorf("no message type in the content type and no default message type")
+	}
+
+	descriptor, err := d.files.FindDescriptorByName(protoreflect.FullName(messageType))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find message type '%s': %w", messageType, err)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, "", fmt.Errorf("'%s' is not a message type", messageType)
+	}
+
+	message := dynamicpb.NewMessage(messageDescriptor)
+	if err := proto.Unmarshal(body, message); err != nil {
+		return nil, "", err
+	}
+	decoded, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, "application/json", nil
+}
@@ -0,0 +1,64 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// jsonDecoder pretty-prints JSON bodies
+type jsonDecoder struct{}
+
+func (jsonDecoder) Name() string { return "json" }
+
+func (jsonDecoder) Match(mediaType string) bool {
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (jsonDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		return nil, "", err
+	}
+	return indented.Bytes(), contentType, nil
+}
+
+// xmlDecoder pretty-prints XML bodies
+type xmlDecoder struct{}
+
+func (xmlDecoder) Name() string { return "xml" }
+
+func (xmlDecoder) Match(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func (xmlDecoder) Decode(body []byte, contentType string) ([]byte, string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	var indented bytes.Buffer
+	encoder := xml.NewEncoder(&indented)
+	encoder.Indent("", "  ")
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		// raw tokens keep namespace prefixes as written, whitespace between elements is replaced by the encoder's indentation
+		if data, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, "", err
+	}
+	return indented.Bytes(), contentType, nil
+}
@@ -0,0 +1,119 @@
+// Package logging builds the slog loggers shared by the CLI and the API
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"dlqt/internal/redact"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestIDKey is the log attribute carrying the request ID
+const RequestIDKey = "request_id"
+
+// Options for New, the zero value logs text at info level
+type Options struct {
+	Format string
+	Level  slog.Level
+	// Redactor masks its pattern matches in the message, string attributes and errors of every record
+	Redactor *redact.Redactor
+}
+
+// ParseLevel parses a level name such as "debug", "info", "warn" or "error", case-insensitively
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// ParseFormat validates a log format, an empty value means text
+func ParseFormat(value string) (string, error) {
+	switch format := strings.ToLower(value); format {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("invalid log format %q, expected %s or %s", value, FormatText, FormatJSON)
+	}
+}
+
+// New creates a logger writing to w, which adds the request ID and trace ID found in the context of each record
+func New(w io.Writer, options *Options) *slog.Logger {
+	if options == nil {
+		options = &Options{}
+	}
+	handlerOptions := &slog.HandlerOptions{Level: options.Level}
+	if redactor := options.Redactor; redactor.Enabled() {
+		handlerOptions.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+			switch value := attr.Value.Any().(type) {
+			case string:
+				attr.Value = slog.StringValue(redactor.Text(value))
+			case error:
+				attr.Value = slog.StringValue(redactor.Text(value.Error()))
+			}
+			return attr
+		}
+	}
+	var handler slog.Handler
+	if options.Format == FormatJSON {
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOptions)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Setup makes a logger created by New the default, which also routes the standard log package through it
+func Setup(w io.Writer, options *Options) {
+	slog.SetDefault(New(w, options))
+}
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID stored by WithRequestID, or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// contextHandler adds context values to records logged with the *Context logger methods
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"dlqt/internal/redact"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"Debug", "debug", slog.LevelDebug, false},
+		{"UpperCase", "WARN", slog.LevelWarn, false},
+		{"Error", "error", slog.LevelError, false},
+		{"Invalid", "verbose", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected level %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"Empty", "", FormatText, false},
+		{"Text", "text", FormatText, false},
+		{"JSON", "JSON", FormatJSON, false},
+		{"Invalid", "logfmt", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected format %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &Options{Format: FormatJSON, Level: slog.LevelInfo})
+
+	logger.DebugContext(context.Background(), "dropped")
+	logger.With("entity", "orders").InfoContext(WithRequestID(context.Background(), "abc123"), "kept")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 record above the level, got %d: %s", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("failed to decode record: %v", err)
+	}
+	if record["msg"] != "kept" || record["entity"] != "orders" || record[RequestIDKey] != "abc123" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestNewRedactor(t *testing.T) {
+	redactor, err := redact.New(&redact.Rules{Patterns: []string{`\d{4}-\d{4}`}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	var buf bytes.Buffer
+	logger := New(&buf, &Options{Redactor: redactor})
+
+	logger.Info("card 1234-5678", "note", "call 1234-5678", "error", errors.New("bad card 1234-5678"))
+
+	if strings.Contains(buf.String(), "1234-5678") || strings.Count(buf.String(), redact.Mask) != 3 {
+		t.Errorf("expected message, attribute and error to be redacted, got %s", buf.String())
+	}
+}
@@ -3,9 +3,12 @@ package msal
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/AzureAD/microsoft-authentication-library-for-go/apps/public"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type MSALConfig struct {
@@ -15,11 +18,16 @@ type MSALConfig struct {
 	CacheFile string // Add this for configurability
 }
 
-type APIConfig struct {
-	APIEndpoint string
-}
+func GetToken(ctx context.Context, config *MSALConfig) (token string, err error) {
+	ctx, span := otel.Tracer("dlqt/internal/msal").Start(ctx, "msal.get_token")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-func GetToken(ctx context.Context, config *MSALConfig) (string, error) {
 	// set up cache to persist tokens
 	cacheAccessor := NewCacheAccessor(config.CacheFile)
 
@@ -32,17 +40,19 @@ func GetToken(ctx context.Context, config *MSALConfig) (string, error) {
 	// check for cached accounts
 	accounts, err := client.Accounts(ctx)
 	if err != nil {
-		log.Println("no cached accounts, proceeding to interactive login")
+		slog.DebugContext(ctx, "no cached accounts, proceeding to interactive login")
 	} else if len(accounts) > 0 {
 		// silent token acquisition using the first account
 		result, err := client.AcquireTokenSilent(ctx, []string{config.Scope}, public.WithSilentAccount(accounts[0]))
 		if err == nil {
+			span.SetAttributes(attribute.Bool("dlqt.msal.silent", true))
 			return result.AccessToken, nil
 		}
-		log.Println("silent acquisition failed, proceeding to interactive")
+		slog.InfoContext(ctx, "silent token acquisition failed, proceeding to interactive login", "error", err)
 	}
 
 	// interactive token acquisition
+	span.SetAttributes(attribute.Bool("dlqt.msal.silent", false))
 	result, err := client.AcquireTokenInteractive(ctx, []string{config.Scope})
 	if err != nil {
 		return "", fmt.Errorf("failed to acquire token interactively: %w", err)
@@ -0,0 +1,237 @@
+// Package redact masks sensitive values in message bodies, application properties and logs
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Mask replaces redacted values
+const Mask = "[REDACTED]"
+
+// Rules select the values to redact, an empty rule set redacts nothing
+type Rules struct {
+	// JSONPaths are dot-separated paths into JSON bodies, "*" matches any key or array index, e.g. "customer.email"
+	// or "items.*.card", a leading "$." is optional
+	JSONPaths []string `json:"jsonPaths"`
+	// Patterns are regular expressions whose matches are masked in text bodies, JSON string values, string
+	// application properties, dead letter error descriptions and log lines
+	Patterns []string `json:"patterns"`
+	// Properties are application property names whose values are masked, globs matched case-insensitively
+	Properties []string `json:"properties"`
+}
+
+// Validate reports every problem with the rules at once
+func (r *Rules) Validate() error {
+	var errs []error
+	for _, jsonPath := range r.JSONPaths {
+		if len(splitJSONPath(jsonPath)) == 0 {
+			errs = append(errs, fmt.Errorf("JSON path '%s' is empty", jsonPath))
+		}
+	}
+	for _, pattern := range r.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern '%s': %w", pattern, err))
+		}
+	}
+	for _, pattern := range r.Properties {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("property pattern '%s': %w", pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redactor applies Rules, a nil Redactor leaves every value unchanged
+type Redactor struct {
+	paths      [][]string
+	patterns   []*regexp.Regexp
+	properties []string
+}
+
+// New compiles the rules, nil or empty rules return a nil Redactor
+func New(rules *Rules) (*Redactor, error) {
+	if rules == nil || len(rules.JSONPaths)+len(rules.Patterns)+len(rules.Properties) == 0 {
+		return nil, nil
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Redactor{}
+	for _, jsonPath := range rules.JSONPaths {
+		r.paths = append(r.paths, splitJSONPath(jsonPath))
+	}
+	for _, pattern := range rules.Patterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(pattern))
+	}
+	for _, pattern := range rules.Properties {
+		r.properties = append(r.properties, strings.ToLower(pattern))
+	}
+	return r, nil
+}
+
+// Load reads a JSON rules file, an empty file name returns a nil Redactor
+func Load(file string) (*Redactor, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction file: %w", err)
+	}
+	defer f.Close()
+
+	var rules Rules
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction file '%s': %w", file, err)
+	}
+	r, err := New(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction file '%s': %w", file, err)
+	}
+	return r, nil
+}
+
+// Enabled reports whether any rule is configured
+func (r *Redactor) Enabled() bool {
+	return r != nil
+}
+
+func splitJSONPath(jsonPath string) []string {
+	jsonPath = strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+	if jsonPath == "" {
+		return nil
+	}
+	return strings.Split(jsonPath, ".")
+}
+
+// Text masks the pattern matches in a string
+func (r *Redactor) Text(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, pattern := range r.patterns {
+		s = pattern.ReplaceAllString(s, Mask)
+	}
+	return s
+}
+
+// Body masks a text body. JSON bodies have their JSON paths masked too and are re-encoded with sorted keys, keeping
+// their indentation. Bodies that claim to be JSON but don't parse, e.g. truncated ones, are treated as text.
+func (r *Redactor) Body(body []byte, isJSON bool) []byte {
+	if r == nil {
+		return body
+	}
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err == nil {
+			for _, segments := range r.paths {
+				value = r.maskPath(value, segments)
+			}
+			value = r.maskStrings(value)
+			redacted, err := marshal(value, bytes.Contains(body, []byte("\n")))
+			if err == nil {
+				return redacted
+			}
+		}
+	}
+	return []byte(r.Text(string(body)))
+}
+
+// marshal encodes without HTML escaping, indented like json.Indent with two spaces when indent is set
+func marshal(value any, indent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if indent {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// maskPath replaces the values at the path, missing keys and type mismatches are ignored
+func (r *Redactor) maskPath(value any, segments []string) any {
+	if len(segments) == 0 {
+		return Mask
+	}
+	segment, rest := segments[0], segments[1:]
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if segment == "*" || segment == key {
+				v[key] = r.maskPath(child, rest)
+			}
+		}
+	case []any:
+		index, err := strconv.Atoi(segment)
+		for i, child := range v {
+			if segment == "*" || (err == nil && index == i) {
+				v[i] = r.maskPath(child, rest)
+			}
+		}
+	}
+	return value
+}
+
+// maskStrings applies the patterns to every string value of a decoded JSON document
+func (r *Redactor) maskStrings(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.Text(v)
+	case map[string]any:
+		for key, child := range v {
+			v[key] = r.maskStrings(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = r.maskStrings(child)
+		}
+	}
+	return value
+}
+
+// Properties returns a copy of the application properties with matching names masked and patterns applied to
+// string values
+func (r *Redactor) Properties(properties map[string]any) map[string]any {
+	if r == nil || properties == nil {
+		return properties
+	}
+	redacted := make(map[string]any, len(properties))
+	for name, value := range properties {
+		if r.matchesProperty(name) {
+			redacted[name] = Mask
+			continue
+		}
+		if s, ok := value.(string); ok {
+			value = r.Text(s)
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func (r *Redactor) matchesProperty(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range r.properties {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package redact
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRedactorBody(t *testing.T) {
+	redactor, err := New(&Rules{
+		JSONPaths: []string{"$.customer.email", "items.*.card", "tags.0"},
+		Patterns:  []string{`\b\d{3}-\d{2}-\d{4}\b`},
+	})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		isJSON bool
+		want   string
+	}{
+		{"Text", "ssn 123-45-6789 on file", false, "ssn [REDACTED] on file"},
+		{"JSONPath", `{"customer":{"email":"a@example.com","name":"A"}}`, true, `{"customer":{"email":"[REDACTED]","name":"A"}}`},
+		{"Wildcard", `{"items":[{"card":"4111"},{"card":{"number":"4222"}},{"sku":"x"}]}`, true, `{"items":[{"card":"[REDACTED]"},{"card":"[REDACTED]"},{"sku":"x"}]}`},
+		{"ArrayIndex", `{"tags":["secret","public"]}`, true, `{"tags":["[REDACTED]","public"]}`},
+		{"PatternInJSON", `{"note":"ssn 123-45-6789","count":12345678901234567890}`, true, `{"count":12345678901234567890,"note":"ssn [REDACTED]"}`},
+		{"MissingPath", `{"customer":"a@example.com"}`, true, `{"customer":"a@example.com"}`},
+		{"Indented", "{\n  \"customer\": {\n    \"email\": \"a\"\n  }\n}", true, "{\n  \"customer\": {\n    \"email\": \"[REDACTED]\"\n  }\n}"},
+		{"InvalidJSON", `{"note":"123-45-6789`, true, `{"note":"[REDACTED]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactor.Body([]byte(tt.body), tt.isJSON)); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRedactorProperties(t *testing.T) {
+	redactor, err := New(&Rules{Patterns: []string{`secret-\w+`}, Properties: []string{"authorization", "x-pii-*"}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	properties := map[string]any{"Authorization": "Bearer abc", "X-PII-Email": "a@example.com", "note": "secret-42", "count": int64(3)}
+
+	got := redactor.Properties(properties)
+
+	want := map[string]any{"Authorization": Mask, "X-PII-Email": Mask, "note": Mask, "count": int64(3)}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("expected %s to be %v, got %v", name, value, got[name])
+		}
+	}
+	if properties["Authorization"] != "Bearer abc" {
+		t.Error("expected the original properties to be unchanged")
+	}
+}
+
+func TestNilRedactor(t *testing.T) {
+	redactor, err := New(&Rules{})
+	if err != nil || redactor != nil {
+		t.Fatalf("expected nil redactor for empty rules, got %v, %v", redactor, err)
+	}
+	if redactor.Enabled() || redactor.Text("x") != "x" || string(redactor.Body([]byte(`{"a":1}`), true)) != `{"a":1}` {
+		t.Error("expected a nil redactor to leave values unchanged")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"Valid", `{"jsonPaths": ["email"], "patterns": ["\\d+"], "properties": ["x-*"]}`, false},
+		{"InvalidPattern", `{"patterns": ["("]}`, true},
+		{"InvalidPropertyPattern", `{"properties": ["["]}`, true},
+		{"EmptyPath", `{"jsonPaths": ["$."]}`, true},
+		{"UnknownField", `{"paths": ["email"]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "redaction.json")
+			if err := os.WriteFile(file, []byte(tt.data), 0600); err != nil {
+				t.Fatalf("failed to write redaction file: %v", err)
+			}
+			redactor, err := Load(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !redactor.Enabled() {
+				t.Error("expected an enabled redactor")
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": expiry.Unix()}).SignedString([]byte("key"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	token := Token(signed)
+
+	got, ok := token.Expiry()
+	if !ok || !got.Equal(expiry) {
+		t.Errorf("expected expiry %v, got %v (%v)", expiry, got, ok)
+	}
+	if _, ok := Token("opaque").Expiry(); ok {
+		t.Error("expected no expiry for an opaque token")
+	}
+
+	var logs strings.Builder
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("acquired token", "token", token)
+	if strings.Contains(logs.String(), signed) || !strings.Contains(logs.String(), "token.fingerprint="+token.Fingerprint()) {
+		t.Errorf("expected only the fingerprint to be logged, got %s", logs.String())
+	}
+}
@@ -0,0 +1,44 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token is an access token that logs and prints as its fingerprint and expiry, never as the token itself
+type Token string
+
+// Fingerprint identifies the token without revealing it, e.g. to correlate it with a token cached elsewhere
+func (t Token) Fingerprint() string {
+	sum := sha256.Sum256([]byte(t))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// Expiry returns the exp claim of a JWT without verifying the signature, false if there is none
+func (t Token) Expiry() (time.Time, bool) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(string(t), claims); err != nil {
+		return time.Time{}, false
+	}
+	expiry, err := claims.GetExpirationTime()
+	if err != nil || expiry == nil {
+		return time.Time{}, false
+	}
+	return expiry.Time, true
+}
+
+func (t Token) String() string {
+	return t.Fingerprint()
+}
+
+func (t Token) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("fingerprint", t.Fingerprint())}
+	if expiry, ok := t.Expiry(); ok {
+		attrs = append(attrs, slog.Time("expires", expiry))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
 )
 
 func GetClient(namespace string) (*azservicebus.Client, error) {
@@ -19,3 +20,16 @@ func GetClient(namespace string) (*azservicebus.Client, error) {
 	}
 	return client, nil
 }
+
+func GetAdminClient(namespace string) (*admin.Client, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+
+	client, err := admin.NewClient(namespace, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Service Bus admin client for namespace '%s': %w", namespace, err)
+	}
+	return client, nil
+}
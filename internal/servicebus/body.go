@@ -0,0 +1,234 @@
+package servicebus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"dlqt/internal/decode"
+	"dlqt/internal/redact"
+)
+
+// BodyOptions controls how a message body is rendered into a DeadLetterMessage
+type BodyOptions struct {
+	// Encoding forces utf8 or base64, empty detects it from the content type and UTF-8 validity
+	Encoding string
+	// Pretty indents JSON bodies
+	Pretty bool
+	// MaxSize truncates the raw body to this many bytes, 0 means no limit
+	MaxSize int
+	// Decoders renders the body into DecodedBody, nil leaves it empty
+	Decoders *decode.Registry
+	// Decoder forces a decoder by name, empty or decode.Auto picks one
+	Decoder string
+	// Redactor masks sensitive values in the rendered message, nil leaves it unchanged
+	Redactor *redact.Redactor
+}
+
+// content types that are never rendered as text, even if the bytes happen to be valid UTF-8
+var binaryContentTypes = map[string]bool{
+	"application/octet-stream":        true,
+	"application/protobuf":            true,
+	"application/x-protobuf":          true,
+	"application/vnd.google.protobuf": true,
+	"application/gzip":                true,
+	"application/x-gzip":              true,
+	"application/zip":                 true,
+	"application/avro":                true,
+	"avro/binary":                     true,
+}
+
+// mediaType returns the lowercased media type of a content type, without parameters
+func mediaType(contentType *string) string {
+	if contentType == nil {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(*contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(*contentType))
+	}
+	return mediaType
+}
+
+// DetectBodyEncoding picks base64 for binary content types or invalid UTF-8, otherwise utf8
+func DetectBodyEncoding(body []byte, contentType *string) string {
+	if binaryContentTypes[mediaType(contentType)] || !utf8.Valid(body) {
+		return BodyEncodingBase64
+	}
+	return BodyEncodingUTF8
+}
+
+// isJSON reports whether a body is JSON, by content type or by parsing it when no content type is set
+func isJSON(body []byte, contentType *string) bool {
+	switch mediaType := mediaType(contentType); {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"), mediaType == "":
+		return json.Valid(body)
+	default:
+		return false
+	}
+}
+
+// setBody renders a raw body into the message according to the options. Redaction runs on the complete body before
+// it is truncated, as a truncated JSON body can't be parsed to apply the JSON path rules.
+func (m *DeadLetterMessage) setBody(body []byte, contentType *string, options *BodyOptions) {
+	if options == nil {
+		options = &BodyOptions{}
+	}
+
+	m.BodySize = len(body)
+	m.BodyEncoding = options.Encoding
+	if m.BodyEncoding == "" {
+		m.BodyEncoding = DetectBodyEncoding(body, contentType)
+	}
+
+	decodedRedacted := false
+	if options.Decoders != nil {
+		decodedRedacted = m.decode(body, options.Decoders, options.Decoder, options.MaxSize, options.Redactor)
+	}
+	if options.Redactor.Enabled() {
+		if DetectBodyEncoding(body, contentType) == BodyEncodingUTF8 {
+			body = options.Redactor.Body(body, isJSON(body, contentType))
+		} else if decodedRedacted {
+			// the raw body of e.g. a gzip message still carries the values masked in its decoded body
+			m.maskBody()
+			return
+		}
+	}
+
+	truncated := options.MaxSize > 0 && len(body) > options.MaxSize
+	if m.BodyEncoding == BodyEncodingBase64 {
+		if truncated {
+			body = body[:options.MaxSize]
+		}
+		m.Body = base64.StdEncoding.EncodeToString(body)
+		m.BodyTruncated = truncated
+		return
+	}
+
+	// truncated JSON would no longer be valid, so only complete bodies are indented
+	if options.Pretty && !truncated && isJSON(body, contentType) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		}
+	}
+	if truncated {
+		body = truncateUTF8(body, options.MaxSize)
+	}
+	m.Body = string(body)
+	m.BodyTruncated = truncated
+}
+
+// maskBody replaces the whole body with the redaction mask, in the message's body encoding
+func (m *DeadLetterMessage) maskBody() {
+	m.BodyTruncated = false
+	if m.BodyEncoding == BodyEncodingBase64 {
+		m.Body = base64.StdEncoding.EncodeToString([]byte(redact.Mask))
+	} else {
+		m.Body = redact.Mask
+	}
+}
+
+// Redact masks sensitive values in the body, decoded body, application properties and dead letter error
+// description, e.g. after receiving the message from the API. Binary bodies are left unchanged unless their decoded
+// body was redacted, then the whole body is masked. BodySize keeps the original size.
+func (m *DeadLetterMessage) Redact(r *redact.Redactor) {
+	if !r.Enabled() {
+		return
+	}
+	m.redactMetadata(r)
+	decodedRedacted := false
+	if m.DecodedBody != "" {
+		decoded := string(r.Body([]byte(m.DecodedBody), json.Valid([]byte(m.DecodedBody))))
+		decodedRedacted = decoded != m.DecodedBody
+		m.DecodedBody = decoded
+	}
+	body, err := m.DecodeBody()
+	switch {
+	case err == nil && DetectBodyEncoding(body, m.ContentType) == BodyEncodingUTF8:
+		redacted := r.Body(body, isJSON(body, m.ContentType))
+		if m.BodyEncoding == BodyEncodingBase64 {
+			m.Body = base64.StdEncoding.EncodeToString(redacted)
+		} else {
+			m.Body = string(redacted)
+		}
+	case decodedRedacted:
+		m.maskBody()
+	}
+}
+
+// redactMetadata masks the application properties and dead letter error description
+func (m *DeadLetterMessage) redactMetadata(r *redact.Redactor) {
+	if !r.Enabled() {
+		return
+	}
+	m.ApplicationProperties = r.Properties(m.ApplicationProperties)
+	if m.DeadLetterErrorDescription != nil {
+		description := r.Text(*m.DeadLetterErrorDescription)
+		m.DeadLetterErrorDescription = &description
+	}
+}
+
+// truncateUTF8 backs off to a rune boundary so the truncated body stays valid UTF-8
+func truncateUTF8(body []byte, maxSize int) []byte {
+	end := maxSize
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return body[:end]
+}
+
+// Decode renders the body into DecodedBody with a decoder from the registry, e.g. after receiving the message from the API
+func (m *DeadLetterMessage) Decode(registry *decode.Registry, name string) {
+	m.DecodedBody, m.DecodedBodyTruncated, m.Decoders, m.DecodeError = "", false, nil, ""
+	if m.BodyTruncated {
+		m.DecodeError = "body is truncated"
+		return
+	}
+	body, err := m.DecodeBody()
+	if err != nil {
+		m.DecodeError = err.Error()
+		return
+	}
+	m.decode(body, registry, name, 0, nil)
+}
+
+// decode renders the body into DecodedBody, redacting it before truncation. It reports whether redaction changed it.
+func (m *DeadLetterMessage) decode(body []byte, registry *decode.Registry, name string, maxSize int, redactor *redact.Redactor) bool {
+	contentType := ""
+	if m.ContentType != nil {
+		contentType = *m.ContentType
+	}
+
+	result, err := registry.Decode(body, contentType, m.ApplicationProperties, name)
+	if errors.Is(err, decode.ErrNoDecoder) {
+		return false
+	}
+	if err != nil {
+		m.DecodeError = err.Error()
+		return false
+	}
+
+	decoded := result.Body
+	if !utf8.Valid(decoded) {
+		m.DecodeError = "decoded body is not valid UTF-8"
+		return false
+	}
+	redacted := false
+	if redactor.Enabled() {
+		masked := redactor.Body(decoded, json.Valid(decoded))
+		redacted = !bytes.Equal(masked, decoded)
+		decoded = masked
+	}
+	if maxSize > 0 && len(decoded) > maxSize {
+		decoded = truncateUTF8(decoded, maxSize)
+		m.DecodedBodyTruncated = true
+	}
+	m.DecodedBody = string(decoded)
+	m.Decoders = result.Decoders
+	return redacted
+}
@@ -0,0 +1,244 @@
+package servicebus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"dlqt/internal/decode"
+	"dlqt/internal/redact"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestNewDeadLetterMessageBody(t *testing.T) {
+	gzipHeader := []byte{0x1f, 0x8b, 0x08, 0x00}
+	redactor, err := redact.New(&redact.Rules{JSONPaths: []string{"card"}, Patterns: []string{`\d{4}-\d{4}`}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		body          []byte
+		contentType   *string
+		options       *BodyOptions
+		wantBody      string
+		wantEncoding  string
+		wantTruncated bool
+	}{
+		{
+			name:         "Text",
+			body:         []byte("plain text"),
+			wantBody:     "plain text",
+			wantEncoding: BodyEncodingUTF8,
+		},
+		{
+			name:         "InvalidUTF8",
+			body:         gzipHeader,
+			wantBody:     base64.StdEncoding.EncodeToString(gzipHeader),
+			wantEncoding: BodyEncodingBase64,
+		},
+		{
+			name:         "BinaryContentType",
+			body:         []byte("valid utf8"),
+			contentType:  to.Ptr("application/x-protobuf"),
+			wantBody:     base64.StdEncoding.EncodeToString([]byte("valid utf8")),
+			wantEncoding: BodyEncodingBase64,
+		},
+		{
+			name:         "ForcedEncoding",
+			body:         []byte("text"),
+			options:      &BodyOptions{Encoding: BodyEncodingBase64},
+			wantBody:     base64.StdEncoding.EncodeToString([]byte("text")),
+			wantEncoding: BodyEncodingBase64,
+		},
+		{
+			name:         "PrettyJSON",
+			body:         []byte(`{"a":1}`),
+			contentType:  to.Ptr("application/json; charset=utf-8"),
+			options:      &BodyOptions{Pretty: true},
+			wantBody:     "{\n  \"a\": 1\n}",
+			wantEncoding: BodyEncodingUTF8,
+		},
+		{
+			name:         "PrettyDetectedJSON",
+			body:         []byte(`[1,2]`),
+			options:      &BodyOptions{Pretty: true},
+			wantBody:     "[\n  1,\n  2\n]",
+			wantEncoding: BodyEncodingUTF8,
+		},
+		{
+			name:         "PrettyIgnoresText",
+			body:         []byte(`{"a":1}`),
+			contentType:  to.Ptr("text/plain"),
+			options:      &BodyOptions{Pretty: true},
+			wantBody:     `{"a":1}`,
+			wantEncoding: BodyEncodingUTF8,
+		},
+		{
+			name:          "TruncatedText",
+			body:          []byte("héllo"),
+			options:       &BodyOptions{MaxSize: 2},
+			wantBody:      "h",
+			wantEncoding:  BodyEncodingUTF8,
+			wantTruncated: true,
+		},
+		{
+			name:          "TruncatedBinary",
+			body:          gzipHeader,
+			options:       &BodyOptions{MaxSize: 2},
+			wantBody:      base64.StdEncoding.EncodeToString(gzipHeader[:2]),
+			wantEncoding:  BodyEncodingBase64,
+			wantTruncated: true,
+		},
+		{
+			name:         "RedactedPrettyJSON",
+			body:         []byte(`{"card":"4111","note":"call 1234-5678"}`),
+			options:      &BodyOptions{Pretty: true, Redactor: redactor},
+			wantBody:     "{\n  \"card\": \"[REDACTED]\",\n  \"note\": \"call [REDACTED]\"\n}",
+			wantEncoding: BodyEncodingUTF8,
+		},
+		{
+			name:         "RedactedBase64",
+			body:         []byte("call 1234-5678"),
+			options:      &BodyOptions{Encoding: BodyEncodingBase64, Redactor: redactor},
+			wantBody:     base64.StdEncoding.EncodeToString([]byte("call [REDACTED]")),
+			wantEncoding: BodyEncodingBase64,
+		},
+		{
+			name:          "RedactedBeforeTruncation",
+			body:          []byte(`{"card":"4111","x":1}`),
+			options:       &BodyOptions{MaxSize: 15, Redactor: redactor},
+			wantBody:      `{"card":"[REDAC`,
+			wantEncoding:  BodyEncodingUTF8,
+			wantTruncated: true,
+		},
+		{
+			name:         "RedactorIgnoresBinary",
+			body:         gzipHeader,
+			options:      &BodyOptions{Redactor: redactor},
+			wantBody:     base64.StdEncoding.EncodeToString(gzipHeader),
+			wantEncoding: BodyEncodingBase64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := &azservicebus.ReceivedMessage{Body: tt.body, ContentType: tt.contentType}
+			message := NewDeadLetterMessage("namespace", QueueEntity("queue"), received, tt.options)
+
+			if message.Body != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, message.Body)
+			}
+			if message.BodyEncoding != tt.wantEncoding {
+				t.Errorf("expected encoding %q, got %q", tt.wantEncoding, message.BodyEncoding)
+			}
+			if message.BodyTruncated != tt.wantTruncated {
+				t.Errorf("expected truncated %v, got %v", tt.wantTruncated, message.BodyTruncated)
+			}
+			if message.BodySize != len(tt.body) {
+				t.Errorf("expected body size %d, got %d", len(tt.body), message.BodySize)
+			}
+		})
+	}
+}
+
+func TestDeadLetterMessageDecode(t *testing.T) {
+	registry := decode.NewDefaultRegistry()
+
+	t.Run("Decoded", func(t *testing.T) {
+		message := &DeadLetterMessage{Body: `{"a":1}`, ContentType: to.Ptr("application/json")}
+		message.Decode(registry, decode.Auto)
+
+		if want := "{\n  \"a\": 1\n}"; message.DecodedBody != want {
+			t.Errorf("expected decoded body %q, got %q", want, message.DecodedBody)
+		}
+		if message.DecodeError != "" {
+			t.Errorf("expected no decode error, got %q", message.DecodeError)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		message := &DeadLetterMessage{Body: `{"a":`, BodyTruncated: true}
+		message.Decode(registry, decode.Auto)
+
+		if message.DecodedBody != "" || message.DecodeError == "" {
+			t.Errorf("expected decode error for truncated body, got body %q error %q", message.DecodedBody, message.DecodeError)
+		}
+	})
+
+	t.Run("NoDecoder", func(t *testing.T) {
+		message := &DeadLetterMessage{Body: "text", ContentType: to.Ptr("text/plain")}
+		message.Decode(registry, decode.Auto)
+
+		if message.DecodedBody != "" || message.DecodeError != "" {
+			t.Errorf("expected no decoding, got body %q error %q", message.DecodedBody, message.DecodeError)
+		}
+	})
+}
+
+func TestDeadLetterMessageRedact(t *testing.T) {
+	redactor, err := redact.New(&redact.Rules{Patterns: []string{`[\w.]+@[\w.]+`}, Properties: []string{"x-customer-*"}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	message := &DeadLetterMessage{
+		Body:                       "from a@example.com",
+		DecodedBody:                `{"email":"a@example.com"}`,
+		DeadLetterErrorDescription: to.Ptr("rejected a@example.com"),
+		ApplicationProperties:      map[string]any{"X-Customer-ID": "42", "source": "a@example.com", "retries": int64(1)},
+	}
+
+	message.Redact(redactor)
+
+	if message.Body != "from [REDACTED]" || message.DecodedBody != `{"email":"[REDACTED]"}` {
+		t.Errorf("expected redacted bodies, got %q and %q", message.Body, message.DecodedBody)
+	}
+	if *message.DeadLetterErrorDescription != "rejected [REDACTED]" {
+		t.Errorf("expected redacted error description, got %q", *message.DeadLetterErrorDescription)
+	}
+	properties := message.ApplicationProperties
+	if properties["X-Customer-ID"] != redact.Mask || properties["source"] != "[REDACTED]" || properties["retries"] != int64(1) {
+		t.Errorf("unexpected redacted properties: %v", properties)
+	}
+}
+
+func TestNewDeadLetterMessageRedactedGzip(t *testing.T) {
+	redactor, err := redact.New(&redact.Rules{JSONPaths: []string{"card"}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(`{"card":"4111"}`))
+	writer.Close()
+	received := &azservicebus.ReceivedMessage{Body: compressed.Bytes(), ContentType: to.Ptr("application/gzip")}
+
+	message := NewDeadLetterMessage("namespace", QueueEntity("queue"), received, &BodyOptions{
+		Decoders: decode.NewDefaultRegistry(),
+		Redactor: redactor,
+	})
+
+	if strings.Contains(message.DecodedBody, "4111") || !strings.Contains(message.DecodedBody, redact.Mask) {
+		t.Errorf("expected redacted decoded body, got %q", message.DecodedBody)
+	}
+	if body, _ := message.DecodeBody(); string(body) != redact.Mask {
+		t.Errorf("expected the compressed body to be masked, got %q", body)
+	}
+}
+
+func TestExportDeadLetterMessagesRedactedReceive(t *testing.T) {
+	redactor, err := redact.New(&redact.Rules{Properties: []string{"secret"}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	// the mode is rejected before the client is used
+	_, err = ExportDeadLetterMessages(t.Context(), nil, QueueEntity("queue"), nil, &ExportOptions{Mode: ExportModeReceive, Redactor: redactor})
+	if err == nil {
+		t.Error("expected error for a redacted receive-mode export")
+	}
+}
@@ -0,0 +1,101 @@
+package servicebus
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// AnnotationPrefix starts the names of the application properties dlqt stamps onto retriggered messages
+const AnnotationPrefix = "dlqt-"
+
+// application properties stamped onto retriggered messages
+const (
+	RetriggeredAtProperty     = "dlqt-retriggered-at"
+	OriginalMessageIDProperty = "dlqt-original-message-id"
+	RetriggerCountProperty    = "dlqt-retrigger-count"
+	RetriggeredByProperty     = "dlqt-retriggered-by"
+
+	// TargetSubscriptionProperty names the subscription a retriggered topic message is meant for,
+	// subscriptions only honour it if they have a correlation filter on it
+	TargetSubscriptionProperty = "dlqt-target-subscription"
+)
+
+// RetriggerOptions configures how a dead letter message is converted back into a sendable message
+type RetriggerOptions struct {
+	// PreserveMessageID reuses the original message ID, which duplicate detection may drop
+	PreserveMessageID bool
+	// DisableAnnotations skips the dlqt-* application properties
+	DisableAnnotations bool
+	// Annotations are extra application properties set on the retriggered message
+	Annotations map[string]any
+	// TargetSubscription sets the dlqt-target-subscription property when re-publishing to a topic
+	TargetSubscription string
+	// RetriggeredBy sets the dlqt-retriggered-by property to who retriggered the message
+	RetriggeredBy string
+}
+
+// NewRetriggerMessage converts a received message into a message carrying every user-settable field
+func NewRetriggerMessage(message *azservicebus.ReceivedMessage, options *RetriggerOptions) *azservicebus.Message {
+	if options == nil {
+		options = &RetriggerOptions{}
+	}
+
+	// copy application properties so the received message is untouched
+	properties := make(map[string]any, len(message.ApplicationProperties)+len(options.Annotations)+4)
+	for key, value := range message.ApplicationProperties {
+		properties[key] = value
+	}
+
+	if !options.DisableAnnotations {
+		properties[RetriggeredAtProperty] = time.Now().UTC().Format(time.RFC3339)
+		properties[OriginalMessageIDProperty] = message.MessageID
+		properties[RetriggerCountProperty] = retriggerCount(message.ApplicationProperties) + 1
+		if options.RetriggeredBy != "" {
+			properties[RetriggeredByProperty] = options.RetriggeredBy
+		}
+	}
+	for key, value := range options.Annotations {
+		properties[key] = value
+	}
+	if options.TargetSubscription != "" {
+		properties[TargetSubscriptionProperty] = options.TargetSubscription
+	}
+
+	newMessage := &azservicebus.Message{
+		ApplicationProperties: properties,
+		Body:                  message.Body,
+		ContentType:           message.ContentType,
+		CorrelationID:         message.CorrelationID,
+		PartitionKey:          message.PartitionKey,
+		ReplyTo:               message.ReplyTo,
+		ReplyToSessionID:      message.ReplyToSessionID,
+		SessionID:             message.SessionID,
+		Subject:               message.Subject,
+		TimeToLive:            message.TimeToLive,
+		To:                    message.To,
+	}
+	if options.PreserveMessageID {
+		messageID := message.MessageID
+		newMessage.MessageID = &messageID
+	}
+	return newMessage
+}
+
+// retriggerCount reads the previous retrigger count, AMQP may decode it as any integer type
+func retriggerCount(properties map[string]any) int64 {
+	switch v := properties[RetriggerCountProperty].(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,91 @@
+package servicebus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestNewRetriggerMessage(t *testing.T) {
+	received := &azservicebus.ReceivedMessage{
+		MessageID:             "original",
+		Body:                  []byte("body"),
+		ApplicationProperties: map[string]any{"tenant": "a", RetriggerCountProperty: int64(2)},
+		ContentType:           to.Ptr("application/json"),
+		CorrelationID:         to.Ptr("correlation"),
+		PartitionKey:          to.Ptr("session"),
+		ReplyTo:               to.Ptr("reply"),
+		ReplyToSessionID:      to.Ptr("reply-session"),
+		SessionID:             to.Ptr("session"),
+		Subject:               to.Ptr("subject"),
+		TimeToLive:            to.Ptr(time.Hour),
+		To:                    to.Ptr("to"),
+	}
+
+	t.Run("CopiesFields", func(t *testing.T) {
+		message := NewRetriggerMessage(received, nil)
+
+		if string(message.Body) != "body" {
+			t.Errorf("expected body %q, got %q", "body", message.Body)
+		}
+		if message.MessageID != nil {
+			t.Errorf("expected no message ID, got %q", *message.MessageID)
+		}
+		for name, got := range map[string]*string{
+			"ContentType":      message.ContentType,
+			"CorrelationID":    message.CorrelationID,
+			"PartitionKey":     message.PartitionKey,
+			"ReplyTo":          message.ReplyTo,
+			"ReplyToSessionID": message.ReplyToSessionID,
+			"SessionID":        message.SessionID,
+			"Subject":          message.Subject,
+			"To":               message.To,
+		} {
+			if got == nil {
+				t.Errorf("expected %s to be copied", name)
+			}
+		}
+		if message.TimeToLive == nil || *message.TimeToLive != time.Hour {
+			t.Errorf("expected time to live %v, got %v", time.Hour, message.TimeToLive)
+		}
+		if message.ApplicationProperties["tenant"] != "a" {
+			t.Errorf("expected application property tenant %q, got %v", "a", message.ApplicationProperties["tenant"])
+		}
+	})
+
+	t.Run("Annotates", func(t *testing.T) {
+		message := NewRetriggerMessage(received, &RetriggerOptions{Annotations: map[string]any{"extra": true}, RetriggeredBy: "user@contoso.com"})
+
+		if message.ApplicationProperties[OriginalMessageIDProperty] != "original" {
+			t.Errorf("expected original message ID %q, got %v", "original", message.ApplicationProperties[OriginalMessageIDProperty])
+		}
+		if message.ApplicationProperties[RetriggerCountProperty] != int64(3) {
+			t.Errorf("expected retrigger count 3, got %v", message.ApplicationProperties[RetriggerCountProperty])
+		}
+		if _, ok := message.ApplicationProperties[RetriggeredAtProperty]; !ok {
+			t.Errorf("expected %s to be set", RetriggeredAtProperty)
+		}
+		if message.ApplicationProperties[RetriggeredByProperty] != "user@contoso.com" {
+			t.Errorf("expected retriggered by %q, got %v", "user@contoso.com", message.ApplicationProperties[RetriggeredByProperty])
+		}
+		if message.ApplicationProperties["extra"] != true {
+			t.Errorf("expected extra annotation to be set")
+		}
+		if received.ApplicationProperties[RetriggerCountProperty] != int64(2) {
+			t.Errorf("expected received message to be unchanged")
+		}
+	})
+
+	t.Run("DisableAnnotations", func(t *testing.T) {
+		message := NewRetriggerMessage(received, &RetriggerOptions{DisableAnnotations: true, PreserveMessageID: true})
+
+		if _, ok := message.ApplicationProperties[OriginalMessageIDProperty]; ok {
+			t.Errorf("expected no %s annotation", OriginalMessageIDProperty)
+		}
+		if message.MessageID == nil || *message.MessageID != "original" {
+			t.Errorf("expected preserved message ID %q, got %v", "original", message.MessageID)
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package servicebus
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -13,6 +15,9 @@ import (
 
 const (
 	queueName           = "queue1"
+	sessionQueueName    = "queue2"
+	topicName           = "topic1"
+	subscriptionName    = "subscription1"
 	testMessage         = "Hello, Testcontainers!"
 	deadLetterMessage   = "Dead letter test message"
 	maxRetries          = 3
@@ -23,6 +28,12 @@ const (
 	retryDelay          = 100 * time.Millisecond
 )
 
+var (
+	queueEntity        = QueueEntity(queueName)
+	subscriptionEntity = SubscriptionEntity(topicName, subscriptionName)
+	sessionEntity      = Entity{Queue: sessionQueueName, RequiresSession: true}
+)
+
 type testHelper struct {
 	t      *testing.T
 	ctx    context.Context
@@ -44,6 +55,37 @@ var serviceBusConfig = `{
                     "RequiresDuplicateDetection": false,
                     "RequiresSession": false
                 }
+            }, {
+                "Name": "queue2",
+                "Properties": {
+                    "DeadLetteringOnMessageExpiration": false,
+                    "DefaultMessageTimeToLive": "PT1H",
+                    "DuplicateDetectionHistoryTimeWindow": "PT20S",
+                    "LockDuration": "PT1M",
+                    "MaxDeliveryCount": 10,
+                    "RequiresDuplicateDetection": false,
+                    "RequiresSession": true
+                }
+            }],
+            "Topics": [{
+                "Name": "topic1",
+                "Properties": {
+                    "DefaultMessageTimeToLive": "PT1H",
+                    "DuplicateDetectionHistoryTimeWindow": "PT20S",
+                    "RequiresDuplicateDetection": false
+                },
+                "Subscriptions": [{
+                    "Name": "subscription1",
+                    "Properties": {
+                        "DeadLetteringOnMessageExpiration": false,
+                        "DefaultMessageTimeToLive": "PT1H",
+                        "LockDuration": "PT1M",
+                        "MaxDeliveryCount": 10,
+                        "ForwardDeadLetteredMessagesTo": "",
+                        "ForwardTo": "",
+                        "RequiresSession": false
+                    }
+                }]
             }]
         }],
         "Logging": {
@@ -93,6 +135,13 @@ func TestServiceBusEmulator(t *testing.T) {
 	t.Run("SendMessage", helper.testSendMessage)
 	t.Run("ReceiveMessage", helper.testReceiveMessage)
 	t.Run("DeadLetterMessage", helper.testDeadLetterMessage)
+	t.Run("PeekDeadLetterMessages", helper.testPeekDeadLetterMessages)
+	t.Run("RetriggerDeadLetterMessageBySequenceNumber", helper.testRetriggerBySequenceNumber)
+	t.Run("BulkRetriggerDeadLetterMessages", helper.testBulkRetrigger)
+	t.Run("RetriggerSubscriptionDeadLetterMessage", helper.testRetriggerSubscription)
+	t.Run("RetriggerSessionDeadLetterMessage", helper.testRetriggerSession)
+	t.Run("ExportImportDeadLetterMessages", helper.testExportImport)
+	t.Run("ScanDeadLetterStats", helper.testScanDeadLetterStats)
 }
 
 func (h *testHelper) testSendMessage(t *testing.T) {
@@ -128,6 +177,309 @@ func (h *testHelper) testDeadLetterMessage(t *testing.T) {
 	}
 }
 
+func (h *testHelper) testPeekDeadLetterMessages(t *testing.T) {
+	h.resetEntity(queueEntity)
+	h.seedDeadLetterMessages(queueEntity, 3)
+
+	// Peek the first page
+	firstPage, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, 2)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(firstPage) != 2 {
+		t.Fatalf("expected 2 peeked messages, got %d", len(firstPage))
+	}
+
+	// Peek the rest from the cursor
+	next := *firstPage[len(firstPage)-1].SequenceNumber + 1
+	secondPage, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, next, 2)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(secondPage) != 1 {
+		t.Fatalf("expected 1 peeked message, got %d", len(secondPage))
+	}
+
+	// Peeking again must not change delivery counts
+	again, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, 3)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	for i, message := range again[:2] {
+		if message.DeliveryCount != firstPage[i].DeliveryCount {
+			t.Errorf("expected delivery count %d, got %d", firstPage[i].DeliveryCount, message.DeliveryCount)
+		}
+	}
+}
+
+func (h *testHelper) testRetriggerBySequenceNumber(t *testing.T) {
+	h.resetEntity(queueEntity)
+	bodies := h.seedDeadLetterMessages(queueEntity, 3)
+
+	peeked, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, 3)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(peeked) != 3 {
+		t.Fatalf("expected 3 peeked messages, got %d", len(peeked))
+	}
+
+	// Retrigger the last message, the ones ahead of it get deferred
+	target := peeked[2]
+	if err := RetriggerDeadLetterMessageBySequenceNumber(h.ctx, h.client, queueEntity, *target.SequenceNumber, nil); err != nil {
+		t.Fatalf("failed to retrigger message: %v", err)
+	}
+
+	if receivedMessage := h.receiveMessage(); receivedMessage != bodies[2] {
+		t.Errorf("expected retriggered message %q, got %q", bodies[2], receivedMessage)
+	}
+
+	remaining, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, 3)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected 2 remaining dead letter messages, got %d", len(remaining))
+	}
+	for _, message := range remaining {
+		if message.State != azservicebus.MessageStateDeferred {
+			t.Errorf("expected skipped message to be deferred rather than abandoned, got state %v", message.State)
+		}
+		if message.DeliveryCount != peeked[0].DeliveryCount {
+			t.Errorf("expected delivery count %d, got %d", peeked[0].DeliveryCount, message.DeliveryCount)
+		}
+	}
+
+	// A deferred message can still be retriggered
+	if err := RetriggerDeadLetterMessageBySequenceNumber(h.ctx, h.client, queueEntity, *remaining[0].SequenceNumber, nil); err != nil {
+		t.Fatalf("failed to retrigger deferred message: %v", err)
+	}
+	if receivedMessage := h.receiveMessage(); receivedMessage != bodies[0] {
+		t.Errorf("expected retriggered message %q, got %q", bodies[0], receivedMessage)
+	}
+
+	// The other deferred message, now at the head of the DLQ, is still fetched
+	fetched, err := FetchDeadLetterMessage(h.ctx, h.client, queueEntity)
+	if err != nil {
+		t.Fatalf("failed to fetch dead letter message: %v", err)
+	}
+	if fetched == nil || *fetched.SequenceNumber != *remaining[1].SequenceNumber {
+		t.Errorf("expected deferred message %d to be fetched, got %v", *remaining[1].SequenceNumber, fetched)
+	}
+}
+
+func (h *testHelper) testBulkRetrigger(t *testing.T) {
+	h.resetEntity(queueEntity)
+	h.seedDeadLetterMessages(queueEntity, 3)
+
+	// Dry run only reports matches
+	result, err := BulkRetriggerDeadLetterMessages(h.ctx, h.client, queueEntity, &BulkRetriggerOptions{
+		Filter: &MessageFilter{DeadLetterReason: "exampleReason"},
+		Limit:  2,
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to dry run bulk retrigger: %v", err)
+	}
+	if len(result.Matched) != 2 || result.Retriggered != 0 {
+		t.Fatalf("expected 2 matched and 0 retriggered, got %d and %d", len(result.Matched), result.Retriggered)
+	}
+
+	// Non-matching filter retriggers nothing
+	result, err = BulkRetriggerDeadLetterMessages(h.ctx, h.client, queueEntity, &BulkRetriggerOptions{
+		Filter: &MessageFilter{DeadLetterReason: "otherReason"},
+	})
+	if err != nil {
+		t.Fatalf("failed to bulk retrigger: %v", err)
+	}
+	if len(result.Matched) != 0 {
+		t.Fatalf("expected 0 matched, got %d", len(result.Matched))
+	}
+
+	result, err = BulkRetriggerDeadLetterMessages(h.ctx, h.client, queueEntity, &BulkRetriggerOptions{
+		Filter: &MessageFilter{DeadLetterReason: "exampleReason"},
+	})
+	if err != nil {
+		t.Fatalf("failed to bulk retrigger: %v", err)
+	}
+	if result.Retriggered != 3 {
+		t.Fatalf("expected 3 retriggered, got %d", result.Retriggered)
+	}
+
+	remaining, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, 3)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected empty dead letter queue, got %d messages", len(remaining))
+	}
+}
+
+func (h *testHelper) testRetriggerSubscription(t *testing.T) {
+	h.resetEntity(subscriptionEntity)
+	bodies := h.seedDeadLetterMessages(subscriptionEntity, 1)
+
+	peeked, err := PeekDeadLetterMessages(h.ctx, h.client, subscriptionEntity, 0, 1)
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(peeked) != 1 {
+		t.Fatalf("expected 1 peeked message, got %d", len(peeked))
+	}
+
+	// Retrigger re-publishes to the topic
+	options := &RetriggerOptions{TargetSubscription: subscriptionName}
+	if err := RetriggerDeadLetterMessageBySequenceNumber(h.ctx, h.client, subscriptionEntity, *peeked[0].SequenceNumber, options); err != nil {
+		t.Fatalf("failed to retrigger message: %v", err)
+	}
+
+	receiver, err := h.client.NewReceiverForSubscription(topicName, subscriptionName, nil)
+	if err != nil {
+		t.Fatalf("failed to create receiver: %v", err)
+	}
+	defer receiver.Close(h.ctx)
+
+	receiveCtx, cancel := context.WithTimeout(h.ctx, receiveTimeout)
+	defer cancel()
+	messages, err := receiver.ReceiveMessages(receiveCtx, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to receive messages: %v", err)
+	}
+	if len(messages) == 0 {
+		t.Fatal("no messages received")
+	}
+	if string(messages[0].Body) != bodies[0] {
+		t.Errorf("expected retriggered message %q, got %q", bodies[0], messages[0].Body)
+	}
+	if messages[0].ApplicationProperties[TargetSubscriptionProperty] != subscriptionName {
+		t.Errorf("expected %s %q, got %v", TargetSubscriptionProperty, subscriptionName, messages[0].ApplicationProperties[TargetSubscriptionProperty])
+	}
+	if err := receiver.CompleteMessage(h.ctx, messages[0], nil); err != nil {
+		t.Fatalf("failed to complete message: %v", err)
+	}
+}
+
+func (h *testHelper) testRetriggerSession(t *testing.T) {
+	h.resetEntity(sessionEntity)
+
+	// Seed messages across sessions
+	messages := []string{t.Name() + " - 1", t.Name() + " - 2", t.Name() + " - 3", t.Name() + " - 4"}
+	if err := SendMessageBatch(h.ctx, h.client, sessionEntity, messages, &SendOptions{Sessions: 2}); err != nil {
+		t.Fatalf("failed to send messages: %v", err)
+	}
+	if err := DeadLetterMessages(h.ctx, h.client, sessionEntity, len(messages)); err != nil {
+		t.Fatalf("failed to dead-letter messages: %v", err)
+	}
+
+	peeked, err := PeekDeadLetterMessages(h.ctx, h.client, sessionEntity, 0, len(messages))
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(peeked) != len(messages) {
+		t.Fatalf("expected %d peeked messages, got %d", len(messages), len(peeked))
+	}
+
+	target := peeked[0]
+	if target.SessionID == nil {
+		t.Fatal("expected dead letter message to have a session ID")
+	}
+	if err := RetriggerDeadLetterMessageBySequenceNumber(h.ctx, h.client, sessionEntity, *target.SequenceNumber, nil); err != nil {
+		t.Fatalf("failed to retrigger message: %v", err)
+	}
+
+	// The retriggered message lands back in its session
+	receiver, err := h.client.AcceptSessionForQueue(h.ctx, sessionQueueName, *target.SessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to accept session: %v", err)
+	}
+	defer receiver.Close(h.ctx)
+
+	receiveCtx, cancel := context.WithTimeout(h.ctx, receiveTimeout)
+	defer cancel()
+	received, err := receiver.ReceiveMessages(receiveCtx, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to receive messages: %v", err)
+	}
+	if len(received) == 0 {
+		t.Fatal("no messages received")
+	}
+	if string(received[0].Body) != string(target.Body) {
+		t.Errorf("expected retriggered message %q, got %q", target.Body, received[0].Body)
+	}
+	if err := receiver.CompleteMessage(h.ctx, received[0], nil); err != nil {
+		t.Fatalf("failed to complete message: %v", err)
+	}
+}
+
+func (h *testHelper) testExportImport(t *testing.T) {
+	h.resetEntity(queueEntity)
+	bodies := h.seedDeadLetterMessages(queueEntity, 3)
+
+	// Export in receive mode drains the dead letter queue
+	var buf bytes.Buffer
+	exported, err := ExportDeadLetterMessages(h.ctx, h.client, queueEntity, &buf, &ExportOptions{Mode: ExportModeReceive})
+	if err != nil {
+		t.Fatalf("failed to export messages: %v", err)
+	}
+	if exported != len(bodies) {
+		t.Fatalf("expected %d exported messages, got %d", len(bodies), exported)
+	}
+	peeked, err := PeekDeadLetterMessages(h.ctx, h.client, queueEntity, 0, len(bodies))
+	if err != nil {
+		t.Fatalf("failed to peek dead letter messages: %v", err)
+	}
+	if len(peeked) != 0 {
+		t.Fatalf("expected empty dead letter queue after export, got %d messages", len(peeked))
+	}
+
+	// Import resumes after the first record
+	offset, err := ImportMessages(h.ctx, h.client, queueEntity, &buf, &ImportOptions{Offset: 1})
+	if err != nil {
+		t.Fatalf("failed to import messages: %v", err)
+	}
+	if offset != len(bodies) {
+		t.Errorf("expected offset %d, got %d", len(bodies), offset)
+	}
+
+	for _, want := range bodies[1:] {
+		if got := h.receiveMessage(); got != want {
+			t.Errorf("expected imported message %q, got %q", want, got)
+		}
+	}
+}
+
+func (h *testHelper) testScanDeadLetterStats(t *testing.T) {
+	h.resetEntity(queueEntity)
+	h.seedDeadLetterMessages(queueEntity, 3)
+
+	stats, err := ScanDeadLetterStats(h.ctx, h.client, queueEntity, &StatsOptions{MaxMessages: 2})
+	if err != nil {
+		t.Fatalf("failed to scan dead letter queue: %v", err)
+	}
+	if stats.Scanned != 2 || !stats.Truncated {
+		t.Errorf("expected 2 scanned and truncated, got %d scanned, truncated %v", stats.Scanned, stats.Truncated)
+	}
+
+	stats, err = ScanDeadLetterStats(h.ctx, h.client, queueEntity, &StatsOptions{MaxMessages: 3})
+	if err != nil {
+		t.Fatalf("failed to scan dead letter queue: %v", err)
+	}
+	if stats.Scanned != 3 || stats.Truncated {
+		t.Errorf("expected 3 scanned and not truncated at the limit, got %d scanned, truncated %v", stats.Scanned, stats.Truncated)
+	}
+
+	stats, err = ScanDeadLetterStats(h.ctx, h.client, queueEntity, nil)
+	if err != nil {
+		t.Fatalf("failed to scan dead letter queue: %v", err)
+	}
+	if stats.Scanned != 3 || stats.Truncated {
+		t.Errorf("expected 3 scanned and not truncated, got %d scanned, truncated %v", stats.Scanned, stats.Truncated)
+	}
+	if len(stats.ByReason) != 1 || stats.ByReason[0].Count != 3 {
+		t.Errorf("expected one reason with 3 messages, got %v", stats.ByReason)
+	}
+}
+
 func setupServiceBusContainer(t *testing.T, ctx context.Context) testcontainers.Container {
 	t.Helper()
 
@@ -291,3 +643,30 @@ func (h *testHelper) receiveDeadLetterMessage() string {
 
 	return string(message.Body)
 }
+
+func (h *testHelper) resetEntity(entity Entity) {
+	h.t.Helper()
+
+	if err := PurgeQueue(h.ctx, h.client, entity); err != nil {
+		h.t.Fatalf("failed to purge queue: %v", err)
+	}
+	if err := PurgeDeadLetterQueue(h.ctx, h.client, entity); err != nil {
+		h.t.Fatalf("failed to purge dead letter queue: %v", err)
+	}
+}
+
+func (h *testHelper) seedDeadLetterMessages(entity Entity, count int) []string {
+	h.t.Helper()
+
+	messages := make([]string, count)
+	for i := range messages {
+		messages[i] = fmt.Sprintf("%s - %d", h.t.Name(), i+1)
+	}
+	if err := SendMessageBatch(h.ctx, h.client, entity, messages, nil); err != nil {
+		h.t.Fatalf("failed to send messages: %v", err)
+	}
+	if err := DeadLetterMessages(h.ctx, h.client, entity, count); err != nil {
+		h.t.Fatalf("failed to dead-letter messages: %v", err)
+	}
+	return messages
+}
@@ -0,0 +1,150 @@
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+)
+
+// how long to wait for the next session before assuming there are none left
+const acceptSessionTimeout = 10 * time.Second
+
+// Entity identifies a queue, or a topic subscription
+type Entity struct {
+	Queue        string
+	Topic        string
+	Subscription string
+	// RequiresSession is set by DetectSession for session-enabled entities
+	RequiresSession bool
+}
+
+// QueueEntity returns the entity for a queue
+func QueueEntity(queue string) Entity {
+	return Entity{Queue: queue}
+}
+
+// SubscriptionEntity returns the entity for a topic subscription
+func SubscriptionEntity(topic string, subscription string) Entity {
+	return Entity{Topic: topic, Subscription: subscription}
+}
+
+// IsSubscription reports whether the entity is a topic subscription
+func (e Entity) IsSubscription() bool {
+	return e.Topic != ""
+}
+
+// Validate checks that exactly one of a queue or a topic and subscription is set
+func (e Entity) Validate() error {
+	switch {
+	case e.Queue != "" && (e.Topic != "" || e.Subscription != ""):
+		return errors.New("either a queue or a topic and subscription must be set, not both")
+	case e.Queue != "":
+		return nil
+	case e.Topic == "" && e.Subscription == "":
+		return errors.New("a queue or a topic and subscription must be set")
+	case e.Topic == "" || e.Subscription == "":
+		return errors.New("a topic and subscription must be set together")
+	}
+	return nil
+}
+
+// ValidateTarget checks that the entity can be sent to, a topic does not need a subscription
+func (e Entity) ValidateTarget() error {
+	if e.Topic != "" && e.Queue == "" {
+		return nil
+	}
+	return e.Validate()
+}
+
+// String describes the entity for logs and errors
+func (e Entity) String() string {
+	if e.IsSubscription() && e.Subscription == "" {
+		return fmt.Sprintf("topic '%s'", e.Topic)
+	}
+	if e.IsSubscription() {
+		return fmt.Sprintf("topic '%s' subscription '%s'", e.Topic, e.Subscription)
+	}
+	return fmt.Sprintf("queue '%s'", e.Queue)
+}
+
+// path names the entity as Service Bus does, a subscription as topic/Subscriptions/subscription
+func (e Entity) path() string {
+	if e.IsSubscription() {
+		return e.Topic + "/Subscriptions/" + e.Subscription
+	}
+	return e.Queue
+}
+
+// newReceiver creates a receiver for the queue or subscription
+func (e Entity) newReceiver(client *azservicebus.Client, options *azservicebus.ReceiverOptions) (*azservicebus.Receiver, error) {
+	if e.IsSubscription() {
+		return client.NewReceiverForSubscription(e.Topic, e.Subscription, options)
+	}
+	return client.NewReceiverForQueue(e.Queue, options)
+}
+
+// newSender creates a sender for the queue, or the topic of a subscription
+func (e Entity) newSender(client *azservicebus.Client) (*azservicebus.Sender, error) {
+	if e.IsSubscription() {
+		return client.NewSender(e.Topic, nil)
+	}
+	return client.NewSender(e.Queue, nil)
+}
+
+// DetectSession looks up whether the queue or subscription requires sessions
+func DetectSession(ctx context.Context, client *admin.Client, entity Entity) (_ Entity, err error) {
+	ctx, span := startSpan(ctx, "detect_session", entity)
+	defer func() { endSpan(span, err) }()
+
+	var requiresSession *bool
+	if entity.IsSubscription() {
+		resp, err := client.GetSubscription(ctx, entity.Topic, entity.Subscription, nil)
+		if err != nil {
+			return entity, fmt.Errorf("failed to get properties of %s: %w", entity, err)
+		}
+		if resp == nil {
+			return entity, fmt.Errorf("%s not found", entity)
+		}
+		requiresSession = resp.RequiresSession
+	} else {
+		resp, err := client.GetQueue(ctx, entity.Queue, nil)
+		if err != nil {
+			return entity, fmt.Errorf("failed to get properties of %s: %w", entity, err)
+		}
+		if resp == nil {
+			return entity, fmt.Errorf("%s not found", entity)
+		}
+		requiresSession = resp.RequiresSession
+	}
+
+	entity.RequiresSession = requiresSession != nil && *requiresSession
+	return entity, nil
+}
+
+// acceptNextSession locks the next available session, returning nil when no session becomes available
+func (e Entity) acceptNextSession(ctx context.Context, client *azservicebus.Client) (*azservicebus.SessionReceiver, error) {
+	acceptCtx, cancel := context.WithTimeout(ctx, acceptSessionTimeout)
+	defer cancel()
+
+	var receiver *azservicebus.SessionReceiver
+	var err error
+	if e.IsSubscription() {
+		receiver, err = client.AcceptNextSessionForSubscription(acceptCtx, e.Topic, e.Subscription, nil)
+	} else {
+		receiver, err = client.AcceptNextSessionForQueue(acceptCtx, e.Queue, nil)
+	}
+
+	var sbErr *azservicebus.Error
+	if errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeTimeout {
+		return nil, nil
+	} else if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to accept next session for %s: %w", e, err)
+	}
+	return receiver, nil
+}
@@ -0,0 +1,304 @@
+package servicebus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"dlqt/internal/redact"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"golang.org/x/time/rate"
+)
+
+// ExportMode selects whether exported dead letters stay in the dead letter queue
+type ExportMode string
+
+const (
+	// ExportModePeek leaves exported messages in the dead letter queue
+	ExportModePeek ExportMode = "peek"
+	// ExportModeReceive completes each message once it has been written
+	ExportModeReceive ExportMode = "receive"
+)
+
+// ExportOptions configures ExportDeadLetterMessages
+type ExportOptions struct {
+	// Namespace is recorded on every exported message
+	Namespace string
+	// Mode defaults to ExportModePeek
+	Mode ExportMode
+	// MaxMessages caps the number of exported messages, 0 means no limit
+	MaxMessages int
+	// Redactor masks sensitive values in the exported records, which can then no longer be replayed faithfully.
+	// It is only allowed with ExportModePeek so the original messages stay in the dead letter queue.
+	Redactor *redact.Redactor
+	// Sync is called in ExportModeReceive before a page of written messages is completed, it should flush any
+	// buffering writer and sync the file so a crash can't lose completed messages. nil relies on w alone.
+	Sync func() error
+}
+
+// ExportDeadLetterMessages writes each dead letter to w as one NDJSON DeadLetterMessage line with a base64 body,
+// returning the number of messages written
+func ExportDeadLetterMessages(ctx context.Context, client *azservicebus.Client, entity Entity, w io.Writer, options *ExportOptions) (_ int, err error) {
+	ctx, span := startSpan(ctx, "export", entity)
+	defer func() { endSpan(span, err) }()
+
+	if options == nil {
+		options = &ExportOptions{}
+	}
+	mode := options.Mode
+	if mode == "" {
+		mode = ExportModePeek
+	}
+	if mode != ExportModePeek && mode != ExportModeReceive {
+		return 0, fmt.Errorf("unknown export mode '%s'", mode)
+	}
+	if mode == ExportModeReceive && options.Redactor.Enabled() {
+		return 0, errors.New("redacted exports must use peek mode, receiving would discard the unredacted messages")
+	}
+
+	// Create receiver for dead-letter queue
+	receiverOptions := &azservicebus.ReceiverOptions{
+		SubQueue: azservicebus.SubQueueDeadLetter,
+	}
+	receiver, err := entity.newReceiver(client, receiverOptions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
+	}
+	defer receiver.Close(ctx)
+
+	encoder := json.NewEncoder(w)
+	exported := 0
+	write := func(message *azservicebus.ReceivedMessage) error {
+		// bodies are always base64 so any payload survives the round trip unchanged
+		record := NewDeadLetterMessage(options.Namespace, entity, message, &BodyOptions{Encoding: BodyEncodingBase64, Redactor: options.Redactor})
+		record.ApplicationPropertyTypes = propertyTypes(record.ApplicationProperties)
+		record.Redacted = options.Redactor.Enabled()
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write message %s: %w", message.MessageID, err)
+		}
+		exported++
+		return nil
+	}
+
+	// Page through the DLQ, a received page is completed so the next page starts from the beginning again
+	const pageSize = 250
+	var from int64
+	for options.MaxMessages <= 0 || exported < options.MaxMessages {
+		limit := pageSize
+		if options.MaxMessages > 0 {
+			limit = min(limit, options.MaxMessages-exported)
+		}
+
+		messages, err := peekMessages(ctx, receiver, from, limit)
+		if err != nil {
+			return exported, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		if mode == ExportModePeek {
+			for _, message := range messages {
+				if err := write(message); err != nil {
+					return exported, err
+				}
+			}
+			from = *messages[len(messages)-1].SequenceNumber + 1
+		} else {
+			// complete the page only once its records are durable, a crash before then leaves them in the DLQ
+			var written []*azservicebus.ReceivedMessage
+			err := receiveDeadLetterMessages(ctx, receiver, messages, func(message *azservicebus.ReceivedMessage) error {
+				if err := write(message); err != nil {
+					return err
+				}
+				written = append(written, message)
+				return nil
+			})
+			if err != nil {
+				return exported, err
+			}
+			if options.Sync != nil {
+				if err := options.Sync(); err != nil {
+					return exported, fmt.Errorf("failed to sync export before completing messages: %w", err)
+				}
+			}
+			for _, message := range written {
+				if err := receiver.CompleteMessage(ctx, message, nil); err != nil {
+					return exported, fmt.Errorf("failed to complete DLQ message: %w", err)
+				}
+			}
+		}
+
+		if len(messages) < limit {
+			break
+		}
+	}
+
+	slog.InfoContext(ctx, "exported messages from DLQ", "entity", entity.String(), "count", exported)
+	return exported, nil
+}
+
+// ImportOptions configures ImportMessages
+type ImportOptions struct {
+	// RatePerSecond limits how many messages are sent per second, 0 means no limit
+	RatePerSecond float64
+	// Offset skips this many records, to resume an earlier import. Blank lines aren't records.
+	Offset int
+	// Retrigger configures the resent messages, its retrigger annotations are always disabled as imported messages
+	// are restored rather than retriggered
+	Retrigger *RetriggerOptions
+	// AllowRedacted sends records of redacted exports, whose masked bodies and properties are otherwise rejected
+	AllowRedacted bool
+}
+
+// ImportMessages resends NDJSON DeadLetterMessage records from r to the queue or topic. It returns the offset of
+// the first record that was not sent, which resumes the import when passed back as ImportOptions.Offset.
+func ImportMessages(ctx context.Context, client *azservicebus.Client, entity Entity, r io.Reader, options *ImportOptions) (_ int, err error) {
+	ctx, span := startSpan(ctx, "import", entity)
+	defer func() { endSpan(span, err) }()
+
+	if options == nil {
+		options = &ImportOptions{}
+	}
+	retrigger := RetriggerOptions{}
+	if options.Retrigger != nil {
+		retrigger = *options.Retrigger
+	}
+	retrigger.DisableAnnotations = true
+
+	slog.DebugContext(ctx, "creating sender", "entity", entity.String())
+	sender, err := entity.newSender(client)
+	if err != nil {
+		return options.Offset, fmt.Errorf("failed to create sender for %s: %w", entity, err)
+	}
+	defer sender.Close(ctx)
+
+	// send in batches no larger than one second of the rate limit
+	maxBatchSize := 100
+	limiter := rate.NewLimiter(rate.Inf, maxBatchSize)
+	if options.RatePerSecond > 0 {
+		maxBatchSize = max(1, min(maxBatchSize, int(options.RatePerSecond)))
+		limiter = rate.NewLimiter(rate.Limit(options.RatePerSecond), maxBatchSize)
+	}
+
+	sent := options.Offset
+	var pending []*azservicebus.Message
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := limiter.WaitN(ctx, len(pending)); err != nil {
+			return err
+		}
+		// count the sub-batches sent before a failure so resuming doesn't send them twice
+		batchSent, err := sendBatch(ctx, sender, pending)
+		sent += batchSent
+		if err != nil {
+			return fmt.Errorf("failed to send records %d to %d: %w", sent, sent+len(pending)-batchSent-1, err)
+		}
+		slog.DebugContext(ctx, "imported records", "count", sent-options.Offset)
+		pending = pending[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	// the offset counts records like sent does, so resuming neither skips nor repeats messages
+	records := 0
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		records++
+		if records <= options.Offset {
+			continue
+		}
+
+		received, err := decodeImportRecord(scanner.Bytes(), options.AllowRedacted)
+		if err != nil {
+			return sent, fmt.Errorf("failed to decode record %d: %w", records-1, err)
+		}
+
+		pending = append(pending, NewRetriggerMessage(received, &retrigger))
+		if len(pending) >= maxBatchSize {
+			if err := flush(); err != nil {
+				return sent, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return sent, fmt.Errorf("failed to read records: %w", err)
+	}
+	if err := flush(); err != nil {
+		return sent, err
+	}
+
+	slog.InfoContext(ctx, "imported records", "entity", entity.String(), "count", sent-options.Offset)
+	return sent, nil
+}
+
+// decodeImportRecord decodes one NDJSON record into the message to resend, rejecting redacted records unless allowed
+func decodeImportRecord(line []byte, allowRedacted bool) (*azservicebus.ReceivedMessage, error) {
+	// numbers are decoded as json.Number so properties can be restored to their recorded types
+	var record DeadLetterMessage
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+	if record.Redacted && !allowRedacted {
+		return nil, fmt.Errorf("message '%s' was exported with redaction, its masked body and properties would be sent as they are", record.MessageID)
+	}
+	if err := restorePropertyTypes(record.ApplicationProperties, record.ApplicationPropertyTypes); err != nil {
+		return nil, err
+	}
+	received, err := record.ReceivedMessage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode body: %w", err)
+	}
+	return received, nil
+}
+
+// sendBatch sends messages in as few batches as fit, returning how many were sent even when a later batch fails
+func sendBatch(ctx context.Context, sender *azservicebus.Sender, messages []*azservicebus.Message) (int, error) {
+	batch, err := sender.NewMessageBatch(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create message batch: %w", err)
+	}
+
+	sent := 0
+	for _, message := range messages {
+		err := batch.AddMessage(message, nil)
+		if errors.Is(err, azservicebus.ErrMessageTooLarge) {
+			if batch.NumMessages() == 0 {
+				return sent, fmt.Errorf("message %v is too large to send", message.MessageID)
+			}
+
+			// batch is full, send it and start a new one
+			numMessages := int(batch.NumMessages())
+			if err := sender.SendMessageBatch(ctx, batch, nil); err != nil {
+				return sent, err
+			}
+			sent += numMessages
+			if batch, err = sender.NewMessageBatch(ctx, nil); err != nil {
+				return sent, fmt.Errorf("failed to create message batch: %w", err)
+			}
+			err = batch.AddMessage(message, nil)
+		}
+		if err != nil {
+			return sent, fmt.Errorf("failed to add message to batch: %w", err)
+		}
+	}
+
+	numMessages := int(batch.NumMessages())
+	if err := sender.SendMessageBatch(ctx, batch, nil); err != nil {
+		return sent, err
+	}
+	return sent + numMessages, nil
+}
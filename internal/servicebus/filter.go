@@ -0,0 +1,54 @@
+package servicebus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// MessageFilter selects dead letter messages, empty fields match everything
+type MessageFilter struct {
+	// DeadLetterReason must match exactly
+	DeadLetterReason string
+	// DeadLetterErrorDescription must be a substring of the error description
+	DeadLetterErrorDescription string
+	// EnqueuedAfter and EnqueuedBefore bound the enqueued time window
+	EnqueuedAfter  *time.Time
+	EnqueuedBefore *time.Time
+	// Subject must match exactly
+	Subject string
+	// ApplicationProperties must all be present with matching string values
+	ApplicationProperties map[string]string
+}
+
+// Matches reports whether a message satisfies every field of the filter
+func (f *MessageFilter) Matches(message *azservicebus.ReceivedMessage) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.DeadLetterReason != "" && (message.DeadLetterReason == nil || *message.DeadLetterReason != f.DeadLetterReason) {
+		return false
+	}
+	if f.DeadLetterErrorDescription != "" && (message.DeadLetterErrorDescription == nil || !strings.Contains(*message.DeadLetterErrorDescription, f.DeadLetterErrorDescription)) {
+		return false
+	}
+	if f.EnqueuedAfter != nil && (message.EnqueuedTime == nil || message.EnqueuedTime.Before(*f.EnqueuedAfter)) {
+		return false
+	}
+	if f.EnqueuedBefore != nil && (message.EnqueuedTime == nil || !message.EnqueuedTime.Before(*f.EnqueuedBefore)) {
+		return false
+	}
+	if f.Subject != "" && (message.Subject == nil || *message.Subject != f.Subject) {
+		return false
+	}
+	for key, expected := range f.ApplicationProperties {
+		value, ok := message.ApplicationProperties[key]
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package servicebus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestMessageFilterMatches(t *testing.T) {
+	enqueued := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := &azservicebus.ReceivedMessage{
+		DeadLetterReason:           to.Ptr("MaxDeliveryCountExceeded"),
+		DeadLetterErrorDescription: to.Ptr("Message could not be consumed after 10 delivery attempts."),
+		EnqueuedTime:               &enqueued,
+		Subject:                    to.Ptr("order.created"),
+		ApplicationProperties:      map[string]any{"tenant": "contoso", "attempt": int64(3)},
+	}
+
+	tests := []struct {
+		name   string
+		filter *MessageFilter
+		want   bool
+	}{
+		{"Nil", nil, true},
+		{"Empty", &MessageFilter{}, true},
+		{"Reason", &MessageFilter{DeadLetterReason: "MaxDeliveryCountExceeded"}, true},
+		{"ReasonMismatch", &MessageFilter{DeadLetterReason: "TTLExpiredException"}, false},
+		{"ErrorDescription", &MessageFilter{DeadLetterErrorDescription: "10 delivery attempts"}, true},
+		{"ErrorDescriptionMismatch", &MessageFilter{DeadLetterErrorDescription: "timeout"}, false},
+		{"EnqueuedAfter", &MessageFilter{EnqueuedAfter: to.Ptr(enqueued.Add(-time.Hour))}, true},
+		{"EnqueuedAfterMismatch", &MessageFilter{EnqueuedAfter: to.Ptr(enqueued.Add(time.Hour))}, false},
+		{"EnqueuedBefore", &MessageFilter{EnqueuedBefore: to.Ptr(enqueued.Add(time.Hour))}, true},
+		{"EnqueuedBeforeMismatch", &MessageFilter{EnqueuedBefore: &enqueued}, false},
+		{"Subject", &MessageFilter{Subject: "order.created"}, true},
+		{"SubjectMismatch", &MessageFilter{Subject: "order.deleted"}, false},
+		{"Properties", &MessageFilter{ApplicationProperties: map[string]string{"tenant": "contoso", "attempt": "3"}}, true},
+		{"PropertiesMismatch", &MessageFilter{ApplicationProperties: map[string]string{"tenant": "fabrikam"}}, false},
+		{"PropertiesMissing", &MessageFilter{ApplicationProperties: map[string]string{"region": "us"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(message); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,55 +4,87 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 )
 
-func SendMessageBatch(ctx context.Context, client *azservicebus.Client, queue string, messages []string) error {
-	log.Println("creating sender")
-	sender, err := client.NewSender(queue, nil)
+// how long to wait for a batch when receiving a specific dead letter message
+const deadLetterReceiveTimeout = 30 * time.Second
+
+// SendOptions configures SendMessageBatch
+type SendOptions struct {
+	// Sessions spreads messages round-robin across this many session IDs, session-enabled entities use at least one
+	Sessions int
+}
+
+func SendMessageBatch(ctx context.Context, client *azservicebus.Client, entity Entity, messages []string, options *SendOptions) (err error) {
+	ctx, span := startSpan(ctx, "send", entity)
+	defer func() { endSpan(span, err) }()
+
+	sessions := 0
+	if options != nil {
+		sessions = options.Sessions
+	}
+	if entity.RequiresSession && sessions == 0 {
+		sessions = 1
+	}
+
+	slog.DebugContext(ctx, "creating sender", "entity", entity.String())
+	sender, err := entity.newSender(client)
 	if err != nil {
-		return fmt.Errorf("failed to create sender for queue '%s': %w", queue, err)
+		return fmt.Errorf("failed to create sender for %s: %w", entity, err)
 	}
 	defer sender.Close(ctx)
 
-	log.Println("creating message batch")
+	slog.DebugContext(ctx, "creating message batch")
 	batch, err := sender.NewMessageBatch(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create message batch: %w", err)
 	}
 
-	log.Printf("adding %d messages to batch", len(messages))
-	for _, message := range messages {
-		log.Printf("adding message to batch: %s", message)
-		err := batch.AddMessage(&azservicebus.Message{Body: []byte(message)}, nil)
+	slog.DebugContext(ctx, "adding messages to batch", "count", len(messages))
+	for i, message := range messages {
+		slog.DebugContext(ctx, "adding message to batch", "index", i, "bytes", len(message))
+		newMessage := &azservicebus.Message{Body: []byte(message)}
+		if sessions > 0 {
+			newMessage.SessionID = to.Ptr(fmt.Sprintf("session-%d", i%sessions+1))
+		}
+		err := batch.AddMessage(newMessage, nil)
 
 		if errors.Is(err, azservicebus.ErrMessageTooLarge) {
-			log.Print("message batch is full")
+			slog.WarnContext(ctx, "message batch is full", "index", i)
 		}
 	}
 
-	log.Printf("sending batch of %d messages", len(messages))
+	slog.DebugContext(ctx, "sending message batch", "count", len(messages))
 	if err := sender.SendMessageBatch(ctx, batch, nil); err != nil {
 		return fmt.Errorf("failed to send message batch: %w", err)
 	}
 
-	log.Printf("successfully sent batch of %d messages", len(messages))
+	slog.InfoContext(ctx, "sent messages", "entity", entity.String(), "count", len(messages))
 	return nil
 }
 
-func DeadLetterMessages(ctx context.Context, client *azservicebus.Client, queue string, count int) error {
+func DeadLetterMessages(ctx context.Context, client *azservicebus.Client, entity Entity, count int) (err error) {
+	ctx, span := startSpan(ctx, "dead_letter", entity)
+	defer func() { endSpan(span, err) }()
+
 	deadLetterOptions := &azservicebus.DeadLetterOptions{
 		ErrorDescription: to.Ptr("exampleErrorDescription"),
 		Reason:           to.Ptr("exampleReason"),
 	}
 
-	log.Println("creating receiver")
-	receiver, err := client.NewReceiverForQueue(queue, nil)
+	if entity.RequiresSession {
+		return deadLetterSessionMessages(ctx, client, entity, count, deadLetterOptions)
+	}
+
+	slog.DebugContext(ctx, "creating receiver", "entity", entity.String())
+	receiver, err := entity.newReceiver(client, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create receiver for queue '%s': %w", queue, err)
+		return fmt.Errorf("failed to create receiver for %s: %w", entity, err)
 	}
 	defer receiver.Close(ctx)
 
@@ -63,18 +95,17 @@ func DeadLetterMessages(ctx context.Context, client *azservicebus.Client, queue
 		remaining := count - receivedMessages
 		batchSize := min(remaining, maxBatchSize)
 
-		log.Printf("requesting %d messages (remaining: %d)", batchSize, remaining)
+		slog.DebugContext(ctx, "requesting messages", "count", batchSize, "remaining", remaining)
 		messages, err := receiver.ReceiveMessages(ctx, batchSize, nil)
 		if err != nil {
 			return fmt.Errorf("failed to receive messages: %w", err)
 		}
 
-		log.Printf("received %d messages", len(messages))
+		slog.DebugContext(ctx, "received messages", "count", len(messages))
 		receivedMessages += len(messages)
 
-		log.Printf("dead-lettering messages")
 		for _, message := range messages {
-			log.Printf("dead-lettering message: %s", message.MessageID)
+			slog.DebugContext(ctx, "dead-lettering message", "messageID", message.MessageID)
 			err := receiver.DeadLetterMessage(ctx, message, deadLetterOptions)
 			if err != nil {
 				return fmt.Errorf("failed to dead-letter message '%s': %w", message.MessageID, err)
@@ -82,90 +113,347 @@ func DeadLetterMessages(ctx context.Context, client *azservicebus.Client, queue
 		}
 	}
 
-	log.Printf("dead-lettered %d messages", receivedMessages)
+	slog.InfoContext(ctx, "dead-lettered messages", "entity", entity.String(), "count", receivedMessages)
 	return nil
 }
 
-func RetriggerDeadLetterMessage(ctx context.Context, client *azservicebus.Client, queue string, messageID string) error {
+// deadLetterSessionMessages dead-letters messages one session at a time, session-enabled entities need a session receiver
+func deadLetterSessionMessages(ctx context.Context, client *azservicebus.Client, entity Entity, count int, options *azservicebus.DeadLetterOptions) error {
+	receivedMessages := 0
+	for receivedMessages < count {
+		slog.DebugContext(ctx, "accepting next session")
+		receiver, err := entity.acceptNextSession(ctx, client)
+		if err != nil {
+			return err
+		}
+		if receiver == nil {
+			return fmt.Errorf("no more sessions available after dead-lettering %d of %d messages", receivedMessages, count)
+		}
+
+		deadLettered, err := deadLetterSession(ctx, receiver, count-receivedMessages, options)
+		receiver.Close(ctx)
+		receivedMessages += deadLettered
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.InfoContext(ctx, "dead-lettered messages", "entity", entity.String(), "count", receivedMessages)
+	return nil
+}
+
+// deadLetterSession dead-letters up to count messages from one session, stopping when the session is drained
+func deadLetterSession(ctx context.Context, receiver *azservicebus.SessionReceiver, count int, options *azservicebus.DeadLetterOptions) (int, error) {
+	const maxBatchSize = 100
+	deadLettered := 0
+	for deadLettered < count {
+		receiveCtx, cancel := context.WithTimeout(ctx, acceptSessionTimeout)
+		messages, err := receiver.ReceiveMessages(receiveCtx, min(count-deadLettered, maxBatchSize), nil)
+		cancel()
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			break // session drained
+		} else if err != nil {
+			return deadLettered, fmt.Errorf("failed to receive messages: %w", err)
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		for _, message := range messages {
+			slog.DebugContext(ctx, "dead-lettering message", "messageID", message.MessageID)
+			err := receiver.DeadLetterMessage(ctx, message, options)
+			if err != nil {
+				return deadLettered, fmt.Errorf("failed to dead-letter message '%s': %w", message.MessageID, err)
+			}
+			deadLettered++
+		}
+	}
+
+	slog.DebugContext(ctx, "dead-lettered messages from session", "sessionID", receiver.SessionID(), "count", deadLettered)
+	return deadLettered, nil
+}
+
+// RetriggerDeadLetterMessage locates a message in the dead letter queue by message ID and retriggers it
+func RetriggerDeadLetterMessage(ctx context.Context, client *azservicebus.Client, entity Entity, messageID string, options *RetriggerOptions) (err error) {
+	ctx, span := startSpan(ctx, "retrigger_by_message_id", entity)
+	defer func() { endSpan(span, err) }()
+
+	sequenceNumber, err := findDeadLetterSequenceNumber(ctx, client, entity, messageID)
+	if err != nil {
+		return err
+	}
+	return RetriggerDeadLetterMessageBySequenceNumber(ctx, client, entity, sequenceNumber, options)
+}
+
+// RetriggerDeadLetterMessageBySequenceNumber resends one dead letter message to the main queue, or the topic of a subscription, and completes it,
+// without abandoning any other message in the dead letter queue. Messages ahead of it are deferred, keeping their delivery count.
+func RetriggerDeadLetterMessageBySequenceNumber(ctx context.Context, client *azservicebus.Client, entity Entity, sequenceNumber int64, options *RetriggerOptions) (err error) {
+	ctx, span := startSpan(ctx, "retrigger", entity)
+	defer func() { endSpan(span, err) }()
+
 	// Create receiver for dead-letter queue
-	options := &azservicebus.ReceiverOptions{
+	receiverOptions := &azservicebus.ReceiverOptions{
 		SubQueue: azservicebus.SubQueueDeadLetter,
 	}
-	receiver, err := client.NewReceiverForQueue(queue, options)
+	receiver, err := entity.newReceiver(client, receiverOptions)
 	if err != nil {
-		return fmt.Errorf("failed to create DLQ receiver for queue '%s': %w", queue, err)
+		return fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
 	}
 	defer receiver.Close(ctx)
 
-	// Create sender for main queue
-	sender, err := client.NewSender(queue, nil)
+	// Create sender for main queue, or the topic of a subscription
+	sender, err := entity.newSender(client)
 	if err != nil {
-		return fmt.Errorf("failed to create sender for queue '%s': %w", queue, err)
+		return fmt.Errorf("failed to create sender for %s: %w", entity, err)
 	}
 	defer sender.Close(ctx)
 
-	// Receive messages in batches until we find the specific message
-	batchSize := 10
-	maxBatches := 100 // Limit to avoid infinite loop
-	for range maxBatches {
-		messages, err := receiver.ReceiveMessages(ctx, batchSize, nil)
+	// Locate the message via peek
+	peeked, err := receiver.PeekMessages(ctx, 1, &azservicebus.PeekMessagesOptions{
+		FromSequenceNumber: &sequenceNumber,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to peek messages from DLQ: %w", err)
+	}
+	if len(peeked) == 0 || *peeked[0].SequenceNumber != sequenceNumber {
+		return fmt.Errorf("message with sequence number %d not found in DLQ for %s", sequenceNumber, entity)
+	}
+
+	_, err = retriggerDeadLetterMessages(ctx, entity, receiver, sender, peeked, options)
+	return err
+}
+
+// BulkRetriggerOptions selects which dead letter messages BulkRetriggerDeadLetterMessages moves
+type BulkRetriggerOptions struct {
+	// Filter selects messages, nil matches every message
+	Filter *MessageFilter
+	// Limit caps the number of matched messages, 0 means no limit
+	Limit int
+	// DryRun only reports the matched messages
+	DryRun bool
+	// Retrigger configures the resent messages
+	Retrigger *RetriggerOptions
+}
+
+// BulkRetriggerDeadLetterMessages scans the dead letter queue with peek and retriggers every matching message
+func BulkRetriggerDeadLetterMessages(ctx context.Context, client *azservicebus.Client, entity Entity, options *BulkRetriggerOptions) (_ *BulkRetriggerResult, err error) {
+	ctx, span := startSpan(ctx, "bulk_retrigger", entity)
+	defer func() { endSpan(span, err) }()
+
+	if options == nil {
+		options = &BulkRetriggerOptions{}
+	}
+
+	// Create receiver for dead-letter queue
+	receiverOptions := &azservicebus.ReceiverOptions{
+		SubQueue: azservicebus.SubQueueDeadLetter,
+	}
+	receiver, err := entity.newReceiver(client, receiverOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
+	}
+	defer receiver.Close(ctx)
+
+	// Scan the whole DLQ for matching messages
+	const pageSize = 250
+	var matched []*azservicebus.ReceivedMessage
+	var from int64
+	for options.Limit <= 0 || len(matched) < options.Limit {
+		messages, err := peekMessages(ctx, receiver, from, pageSize)
 		if err != nil {
-			return fmt.Errorf("failed to receive messages from DLQ: %w", err)
+			return nil, err
 		}
 
-		if len(messages) == 0 {
-			break // No more messages
+		for _, message := range messages {
+			if options.Filter.Matches(message) {
+				matched = append(matched, message)
+			}
+		}
+
+		if len(messages) < pageSize {
+			break
+		}
+		from = *messages[len(messages)-1].SequenceNumber + 1
+	}
+	if options.Limit > 0 && len(matched) > options.Limit {
+		matched = matched[:options.Limit]
+	}
+	slog.InfoContext(ctx, "matched messages in DLQ", "entity", entity.String(), "count", len(matched))
+
+	result := &BulkRetriggerResult{
+		DryRun:  options.DryRun,
+		Matched: make([]MessageReference, 0, len(matched)),
+	}
+	for _, message := range matched {
+		result.Matched = append(result.Matched, MessageReference{
+			MessageID:        message.MessageID,
+			SequenceNumber:   *message.SequenceNumber,
+			DeadLetterReason: message.DeadLetterReason,
+		})
+	}
+	if options.DryRun || len(matched) == 0 {
+		return result, nil
+	}
+
+	// Create sender for main queue, or the topic of a subscription
+	sender, err := entity.newSender(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sender for %s: %w", entity, err)
+	}
+	defer sender.Close(ctx)
+
+	result.Retriggered, err = retriggerDeadLetterMessages(ctx, entity, receiver, sender, matched, options.Retrigger)
+	return result, err
+}
+
+// retriggerDeadLetterMessages resends and completes each of the peeked dead letter messages, returning how many were retriggered
+func retriggerDeadLetterMessages(ctx context.Context, entity Entity, receiver *azservicebus.Receiver, sender *azservicebus.Sender, peeked []*azservicebus.ReceivedMessage, options *RetriggerOptions) (int, error) {
+	// Session-enabled entities reject messages without a session ID, check before settling anything
+	if entity.RequiresSession {
+		for _, message := range peeked {
+			if message.SessionID == nil {
+				return 0, fmt.Errorf("message %s has no session ID but %s requires sessions", message.MessageID, entity)
+			}
+		}
+	}
+
+	retriggered := 0
+	err := receiveDeadLetterMessages(ctx, receiver, peeked, func(message *azservicebus.ReceivedMessage) error {
+		// Create new message with the same body and properties
+		newMessage := NewRetriggerMessage(message, options)
+		if options == nil || !options.DisableAnnotations {
+			injectTraceContext(ctx, newMessage.ApplicationProperties)
+		}
+
+		// Send to main queue or topic
+		err := sender.SendMessage(ctx, newMessage, nil)
+		if err != nil {
+			return fmt.Errorf("failed to send retriggered message: %w", err)
+		}
+
+		// Complete the original DLQ message
+		err = receiver.CompleteMessage(ctx, message, nil)
+		if err != nil {
+			return fmt.Errorf("failed to complete DLQ message: %w", err)
+		}
+
+		retriggered++
+		slog.DebugContext(ctx, "retriggered message from DLQ", "messageID", message.MessageID, "sequenceNumber", *message.SequenceNumber)
+		return nil
+	})
+	return retriggered, err
+}
+
+// findDeadLetterSequenceNumber pages through the dead letter queue with peek to find the sequence number of a message ID
+func findDeadLetterSequenceNumber(ctx context.Context, client *azservicebus.Client, entity Entity, messageID string) (int64, error) {
+	const pageSize = 250
+	var from int64
+	for {
+		messages, err := PeekDeadLetterMessages(ctx, client, entity, from, pageSize)
+		if err != nil {
+			return 0, err
 		}
 
 		for _, message := range messages {
 			if message.MessageID == messageID {
-				// Found the message, create new message with same body
-				newMessage := &azservicebus.Message{
-					Body: message.Body,
-				}
+				return *message.SequenceNumber, nil
+			}
+		}
 
-				// Send to main queue
-				err = sender.SendMessage(ctx, newMessage, nil)
-				if err != nil {
-					return fmt.Errorf("failed to send retriggered message: %w", err)
-				}
+		if len(messages) < pageSize {
+			return 0, fmt.Errorf("message with ID '%s' not found in DLQ for %s", messageID, entity)
+		}
+		from = *messages[len(messages)-1].SequenceNumber + 1
+	}
+}
 
-				// Complete the original DLQ message
-				err = receiver.CompleteMessage(ctx, message, nil)
-				if err != nil {
-					return fmt.Errorf("failed to complete DLQ message: %w", err)
-				}
+// receiveDeadLetterMessages locks each of the peeked dead letter messages and passes it to handle.
+// Other messages received along the way are deferred as soon as they arrive, which releases their lock and leaves
+// their delivery count untouched, so no locks pile up on a long scan and the receiver moves past them. Deferred
+// messages stay visible to peek, are received by sequence number here and are returned by fetch.
+func receiveDeadLetterMessages(ctx context.Context, receiver *azservicebus.Receiver, peeked []*azservicebus.ReceivedMessage, handle func(*azservicebus.ReceivedMessage) error) error {
+	pending := make(map[int64]bool, len(peeked))
+	var deferred []int64
+	for _, message := range peeked {
+		pending[*message.SequenceNumber] = true
+		if message.State == azservicebus.MessageStateDeferred {
+			deferred = append(deferred, *message.SequenceNumber)
+		}
+	}
 
-				log.Printf("Successfully retriggered message %s from DLQ to main queue", messageID)
-				return nil
-			} else {
-				// Not the target message, abandon it to put back in DLQ
-				err = receiver.AbandonMessage(ctx, message, nil)
-				if err != nil {
-					log.Printf("failed to abandon message %s: %v", message.MessageID, err)
-					// Continue anyway
-				}
+	// Deferred messages can only be received by sequence number
+	const deferredBatchSize = 100
+	for start := 0; start < len(deferred); start += deferredBatchSize {
+		batch := deferred[start:min(start+deferredBatchSize, len(deferred))]
+		messages, err := receiver.ReceiveDeferredMessages(ctx, batch, nil)
+		if err != nil {
+			return fmt.Errorf("failed to receive deferred messages from DLQ: %w", err)
+		}
+		for _, message := range messages {
+			delete(pending, *message.SequenceNumber)
+			if err := handle(message); err != nil {
+				return err
 			}
 		}
 	}
 
-	return fmt.Errorf("message with ID '%s' not found in DLQ for queue '%s' after checking %d messages", messageID, queue, batchSize*maxBatches)
+	// Receive until every target is found, deferring everything else
+	const batchSize = 10
+	for len(pending) > 0 {
+		receiveCtx, cancel := context.WithTimeout(ctx, deadLetterReceiveTimeout)
+		messages, err := receiver.ReceiveMessages(receiveCtx, batchSize, nil)
+		cancel()
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%d messages could not be received from DLQ", len(pending))
+		} else if err != nil {
+			return fmt.Errorf("failed to receive messages from DLQ: %w", err)
+		}
+
+		for _, message := range messages {
+			if pending[*message.SequenceNumber] {
+				delete(pending, *message.SequenceNumber)
+				if err := handle(message); err != nil {
+					return err
+				}
+				continue
+			}
+
+			err := receiver.DeferMessage(ctx, message, nil)
+			if err != nil {
+				return fmt.Errorf("failed to defer message %s: %w", message.MessageID, err)
+			}
+			slog.DebugContext(ctx, "deferred message in DLQ", "messageID", message.MessageID, "sequenceNumber", *message.SequenceNumber)
+		}
+	}
+	return nil
 }
 
 // FetchDeadLetterMessage fetches one message from the dead letter queue
-func FetchDeadLetterMessage(ctx context.Context, client *azservicebus.Client, queue string) (*azservicebus.ReceivedMessage, error) {
+func FetchDeadLetterMessage(ctx context.Context, client *azservicebus.Client, entity Entity) (_ *azservicebus.ReceivedMessage, err error) {
+	ctx, span := startSpan(ctx, "fetch", entity)
+	defer func() { endSpan(span, err) }()
+
 	// Create receiver for dead-letter queue
 	options := &azservicebus.ReceiverOptions{
 		SubQueue: azservicebus.SubQueueDeadLetter,
 	}
-	receiver, err := client.NewReceiverForQueue(queue, options)
+	receiver, err := entity.newReceiver(client, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create DLQ receiver for queue '%s': %w", queue, err)
+		return nil, fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
 	}
 	defer receiver.Close(ctx)
 
-	// Receive one message from DLQ
-	messages, err := receiver.ReceiveMessages(ctx, 1, nil)
+	// A deferred message at the head of the DLQ can only be received by sequence number
+	peeked, err := receiver.PeekMessages(ctx, 1, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek messages from DLQ: %w", err)
+	}
+	var messages []*azservicebus.ReceivedMessage
+	if len(peeked) > 0 && peeked[0].State == azservicebus.MessageStateDeferred {
+		messages, err = receiver.ReceiveDeferredMessages(ctx, []int64{*peeked[0].SequenceNumber}, nil)
+	} else {
+		messages, err = receiver.ReceiveMessages(ctx, 1, nil)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to receive messages from DLQ: %w", err)
 	}
@@ -175,7 +463,7 @@ func FetchDeadLetterMessage(ctx context.Context, client *azservicebus.Client, qu
 	}
 
 	message := messages[0]
-	log.Printf("Fetched message %s from DLQ", message.MessageID)
+	slog.DebugContext(ctx, "fetched message from DLQ", "messageID", message.MessageID)
 
 	// Complete the message to remove it from the DLQ
 	// err = receiver.CompleteMessage(ctx, message, nil)
@@ -185,3 +473,50 @@ func FetchDeadLetterMessage(ctx context.Context, client *azservicebus.Client, qu
 
 	return message, nil
 }
+
+// PeekDeadLetterMessages peeks up to maxMessages messages from the dead letter queue, starting at fromSequence,
+// without locking them or changing their delivery count
+func PeekDeadLetterMessages(ctx context.Context, client *azservicebus.Client, entity Entity, fromSequence int64, maxMessages int) (_ []*azservicebus.ReceivedMessage, err error) {
+	ctx, span := startSpan(ctx, "peek", entity)
+	defer func() { endSpan(span, err) }()
+
+	// Create receiver for dead-letter queue
+	options := &azservicebus.ReceiverOptions{
+		SubQueue: azservicebus.SubQueueDeadLetter,
+	}
+	receiver, err := entity.newReceiver(client, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
+	}
+	defer receiver.Close(ctx)
+
+	peekedMessages, err := peekMessages(ctx, receiver, fromSequence, maxMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "peeked messages from DLQ", "count", len(peekedMessages), "fromSequenceNumber", fromSequence)
+	return peekedMessages, nil
+}
+
+// peekMessages peeks in batches from fromSequence, the service may return fewer messages than requested
+func peekMessages(ctx context.Context, receiver *azservicebus.Receiver, fromSequence int64, maxMessages int) ([]*azservicebus.ReceivedMessage, error) {
+	var peekedMessages []*azservicebus.ReceivedMessage
+	nextSequence := fromSequence
+	for len(peekedMessages) < maxMessages {
+		messages, err := receiver.PeekMessages(ctx, maxMessages-len(peekedMessages), &azservicebus.PeekMessagesOptions{
+			FromSequenceNumber: &nextSequence,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to peek messages from DLQ: %w", err)
+		}
+
+		if len(messages) == 0 {
+			break // No more messages
+		}
+
+		peekedMessages = append(peekedMessages, messages...)
+		nextSequence = *messages[len(messages)-1].SequenceNumber + 1
+	}
+	return peekedMessages, nil
+}
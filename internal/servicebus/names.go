@@ -0,0 +1,66 @@
+package servicebus
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Azure naming rules, see https://learn.microsoft.com/azure/azure-resource-manager/management/resource-name-rules#microsoftservicebus
+var (
+	namespaceNamePattern    = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]{4,48}[a-zA-Z0-9]$`)
+	entityNamePattern       = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]{0,258}[a-zA-Z0-9])?$`)
+	subscriptionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,48}[a-zA-Z0-9])?$`)
+)
+
+// ValidateNamespaceName checks a namespace name, without the .servicebus.windows.net suffix, against Azure's naming rules
+func ValidateNamespaceName(name string) error {
+	if name == "" {
+		return errors.New("a namespace must be set")
+	}
+	if !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("namespace '%s' must be 6-50 letters, numbers and hyphens, starting with a letter and ending with a letter or number", name)
+	}
+	if lower := strings.ToLower(name); strings.HasSuffix(lower, "-sb") || strings.HasSuffix(lower, "-mgmt") {
+		return fmt.Errorf("namespace '%s' must not end with -sb or -mgmt", name)
+	}
+	return nil
+}
+
+// ValidateNames checks that the entity is set and its names follow Azure's naming rules
+func (e Entity) ValidateNames() error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	if !e.IsSubscription() {
+		if !entityNamePattern.MatchString(e.Queue) {
+			return fmt.Errorf("queue '%s' must be 1-260 letters, numbers, periods, hyphens, underscores and slashes, starting and ending with a letter or number", e.Queue)
+		}
+		if hasSubscriptionsSegment(e.Queue) {
+			return fmt.Errorf("queue '%s' must not contain a subscriptions segment, the path of a topic's subscriptions", e.Queue)
+		}
+		return nil
+	}
+	if !entityNamePattern.MatchString(e.Topic) {
+		return fmt.Errorf("topic '%s' must be 1-260 letters, numbers, periods, hyphens, underscores and slashes, starting and ending with a letter or number", e.Topic)
+	}
+	if hasSubscriptionsSegment(e.Topic) {
+		return fmt.Errorf("topic '%s' must not contain a subscriptions segment, the path of a topic's subscriptions", e.Topic)
+	}
+	if !subscriptionNamePattern.MatchString(e.Subscription) {
+		return fmt.Errorf("subscription '%s' must be 1-50 letters, numbers, periods, hyphens and underscores, starting and ending with a letter or number", e.Subscription)
+	}
+	return nil
+}
+
+// hasSubscriptionsSegment reports whether a queue or topic name contains the segment Service Bus addresses
+// subscriptions with, topic/Subscriptions/subscription, so entity paths stay unambiguous
+func hasSubscriptionsSegment(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.EqualFold(segment, "subscriptions") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package servicebus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNamespaceName(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		wantErr   bool
+	}{
+		{"Valid", "sb-dlqt", false},
+		{"Empty", "", true},
+		{"TooShort", "sb-dl", true},
+		{"TooLong", "sb" + strings.Repeat("a", 49), true},
+		{"StartsWithNumber", "1sb-dlqt", true},
+		{"EndsWithHyphen", "sb-dlqt-", true},
+		{"Host", "sb-dlqt.servicebus.windows.net", true},
+		{"ReservedSuffix", "dlqt-sb", true},
+		{"ReservedMgmtSuffix", "dlqt-Mgmt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNamespaceName(tt.namespace); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEntityValidateNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		entity  Entity
+		wantErr bool
+	}{
+		{"Queue", QueueEntity("orders"), false},
+		{"QueuePath", QueueEntity("orders/eu_west.v2"), false},
+		{"EmptyQueue", QueueEntity(""), true},
+		{"QueueStartsWithPeriod", QueueEntity(".orders"), true},
+		{"QueueEndsWithSlash", QueueEntity("orders/"), true},
+		{"QueueInvalidCharacter", QueueEntity("orders?x"), true},
+		{"QueueTooLong", QueueEntity(strings.Repeat("a", 261)), true},
+		{"QueueSubscriptionsSegment", QueueEntity("orders/Subscriptions/eu"), true},
+		{"QueueSubscriptionsPrefix", QueueEntity("orders/subscriptions-eu"), false},
+		{"Subscription", SubscriptionEntity("events", "audit-1"), false},
+		{"SubscriptionSlash", SubscriptionEntity("events", "audit/1"), true},
+		{"SubscriptionTooLong", SubscriptionEntity("events", strings.Repeat("a", 51)), true},
+		{"TopicInvalid", SubscriptionEntity("-events", "audit"), true},
+		{"TopicSubscriptionsSegment", SubscriptionEntity("events/subscriptions/x", "audit"), true},
+		{"MissingSubscription", Entity{Topic: "events"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.entity.ValidateNames(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+)
+
+// DefaultClientIdleTimeout is how long a namespace's clients stay open without requests
+const DefaultClientIdleTimeout = 10 * time.Minute
+
+// ErrClientPoolClosed is returned for clients requested after the pool is closed
+var ErrClientPoolClosed = errors.New("client pool is closed")
+
+// ClientPoolOptions configures a ClientPool
+type ClientPoolOptions struct {
+	// IdleTimeout closes a namespace's clients once they've been unused this long, 0 uses DefaultClientIdleTimeout
+	IdleTimeout time.Duration
+	// Credential authenticates every client, nil creates one DefaultAzureCredential on first use
+	Credential azcore.TokenCredential
+}
+
+// ClientPool shares one client, and so one AMQP connection, per namespace between callers, and one credential
+// and its token cache between namespaces. Receivers and senders are still created per call, they're cheap links on
+// the shared connection, and a receiver's peek cursor and message locks aren't safe to share between requests.
+type ClientPool struct {
+	mu          sync.Mutex
+	credential  azcore.TokenCredential
+	idleTimeout time.Duration
+	namespaces  map[string]*pooledNamespace
+	closed      bool
+	stop        chan struct{}
+}
+
+// pooledNamespace is a namespace's clients, the client is only evicted once every caller has released it
+type pooledNamespace struct {
+	client   *azservicebus.Client
+	admin    *admin.Client
+	refs     int
+	lastUsed time.Time
+}
+
+// NewClientPool creates an empty pool, clients are created on first use and evicted in the background once idle
+func NewClientPool(options *ClientPoolOptions) *ClientPool {
+	if options == nil {
+		options = &ClientPoolOptions{}
+	}
+	p := &ClientPool{
+		credential:  options.Credential,
+		idleTimeout: options.IdleTimeout,
+		namespaces:  map[string]*pooledNamespace{},
+		stop:        make(chan struct{}),
+	}
+	if p.idleTimeout <= 0 {
+		p.idleTimeout = DefaultClientIdleTimeout
+	}
+	go p.evictIdle()
+	return p
+}
+
+// Client returns the shared client of the fully qualified namespace, the caller must call release once done with it
+func (p *ClientPool) Client(namespace string) (client *azservicebus.Client, release func(), err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pooled, err := p.namespace(namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pooled.client == nil {
+		pooled.client, err = azservicebus.NewClient(namespace, p.credential, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create Service Bus client for namespace '%s': %w", namespace, err)
+		}
+	}
+	pooled.refs++
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			pooled.refs--
+			pooled.lastUsed = time.Now()
+		})
+	}
+	return pooled.client, release, nil
+}
+
+// AdminClient returns the shared admin client of the fully qualified namespace, it holds no connection so needs no release
+func (p *ClientPool) AdminClient(namespace string) (*admin.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pooled, err := p.namespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if pooled.admin == nil {
+		pooled.admin, err = admin.NewClient(namespace, p.credential, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Service Bus admin client for namespace '%s': %w", namespace, err)
+		}
+	}
+	return pooled.admin, nil
+}
+
+// namespace returns the pooled clients of a namespace, creating the shared credential on first use. p.mu must be held.
+func (p *ClientPool) namespace(namespace string) (*pooledNamespace, error) {
+	if p.closed {
+		return nil, ErrClientPoolClosed
+	}
+	if p.credential == nil {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		p.credential = cred
+	}
+
+	pooled, ok := p.namespaces[namespace]
+	if !ok {
+		pooled = &pooledNamespace{}
+		p.namespaces[namespace] = pooled
+	}
+	pooled.lastUsed = time.Now()
+	return pooled, nil
+}
+
+// evictIdle periodically closes the clients of namespaces that have been idle longer than the idle timeout
+func (p *ClientPool) evictIdle() {
+	ticker := time.NewTicker(p.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evict(now)
+		}
+	}
+}
+
+// evict closes the clients idle since before now minus the idle timeout, clients in use are kept
+func (p *ClientPool) evict(now time.Time) {
+	p.mu.Lock()
+	var idle []*azservicebus.Client
+	for namespace, pooled := range p.namespaces {
+		if pooled.refs > 0 || now.Sub(pooled.lastUsed) < p.idleTimeout {
+			continue
+		}
+		if pooled.client != nil {
+			idle = append(idle, pooled.client)
+		}
+		delete(p.namespaces, namespace)
+	}
+	p.mu.Unlock()
+
+	for _, client := range idle {
+		if err := client.Close(context.Background()); err != nil {
+			slog.Warn("failed to close idle Service Bus client", "error", err)
+		}
+	}
+}
+
+// Close stops eviction and closes every client, including clients not yet released
+func (p *ClientPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	namespaces := p.namespaces
+	p.namespaces = map[string]*pooledNamespace{}
+	p.mu.Unlock()
+
+	var errs []error
+	for namespace, pooled := range namespaces {
+		if pooled.client == nil {
+			continue
+		}
+		if err := pooled.client.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close Service Bus client for namespace '%s': %w", namespace, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// staticCredential lets clients be created without signing in, they never connect in these tests
+type staticCredential struct{}
+
+func (staticCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestClientPool(t *testing.T) {
+	const namespace = "sb-dlqt.servicebus.windows.net"
+	newPool := func(t *testing.T) *ClientPool {
+		pool := NewClientPool(&ClientPoolOptions{IdleTimeout: time.Minute, Credential: staticCredential{}})
+		t.Cleanup(func() { pool.Close(context.Background()) })
+		return pool
+	}
+
+	t.Run("Reuses", func(t *testing.T) {
+		pool := newPool(t)
+		first, release, err := pool.Client(namespace)
+		if err != nil {
+			t.Fatalf("failed to get client: %v", err)
+		}
+		release()
+		release()
+		second, release, err := pool.Client(namespace)
+		if err != nil {
+			t.Fatalf("failed to get client: %v", err)
+		}
+		defer release()
+		if first != second {
+			t.Error("expected the namespace's client to be reused")
+		}
+		if pool.namespaces[namespace].refs != 1 {
+			t.Errorf("expected a repeated release to count once, got %d refs", pool.namespaces[namespace].refs)
+		}
+
+		other, releaseOther, err := pool.Client("sb-other.servicebus.windows.net")
+		if err != nil {
+			t.Fatalf("failed to get client: %v", err)
+		}
+		defer releaseOther()
+		if other == first {
+			t.Error("expected a client per namespace")
+		}
+	})
+
+	t.Run("EvictsIdle", func(t *testing.T) {
+		pool := newPool(t)
+		first, release, err := pool.Client(namespace)
+		if err != nil {
+			t.Fatalf("failed to get client: %v", err)
+		}
+
+		pool.evict(time.Now().Add(2 * time.Minute))
+		if _, ok := pool.namespaces[namespace]; !ok {
+			t.Fatal("expected a client in use to be kept")
+		}
+
+		release()
+		pool.evict(time.Now().Add(30 * time.Second))
+		if _, ok := pool.namespaces[namespace]; !ok {
+			t.Fatal("expected a recently used client to be kept")
+		}
+
+		pool.evict(time.Now().Add(2 * time.Minute))
+		if _, ok := pool.namespaces[namespace]; ok {
+			t.Fatal("expected an idle client to be evicted")
+		}
+		second, release, err := pool.Client(namespace)
+		if err != nil {
+			t.Fatalf("failed to get client: %v", err)
+		}
+		defer release()
+		if first == second {
+			t.Error("expected a new client after eviction")
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		pool := newPool(t)
+		if _, err := pool.AdminClient(namespace); err != nil {
+			t.Fatalf("failed to get admin client: %v", err)
+		}
+		if err := pool.Close(context.Background()); err != nil {
+			t.Fatalf("failed to close pool: %v", err)
+		}
+		if _, _, err := pool.Client(namespace); !errors.Is(err, ErrClientPoolClosed) {
+			t.Errorf("expected ErrClientPoolClosed, got %v", err)
+		}
+	})
+}
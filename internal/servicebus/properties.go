@@ -0,0 +1,135 @@
+package servicebus
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// application property type names recorded by propertyTypes, the Go type names AMQP decodes properties into
+const (
+	propertyTypeInt     = "int"
+	propertyTypeInt8    = "int8"
+	propertyTypeInt16   = "int16"
+	propertyTypeInt32   = "int32"
+	propertyTypeInt64   = "int64"
+	propertyTypeUint8   = "uint8"
+	propertyTypeUint16  = "uint16"
+	propertyTypeUint32  = "uint32"
+	propertyTypeUint64  = "uint64"
+	propertyTypeFloat32 = "float32"
+	propertyTypeFloat64 = "float64"
+	propertyTypeTime    = "time"
+	propertyTypeBytes   = "bytes"
+)
+
+// propertyTypes records the type of each property JSON can't round-trip, nil when there are none
+func propertyTypes(properties map[string]any) map[string]string {
+	types := map[string]string{}
+	for name, value := range properties {
+		var propertyType string
+		switch value.(type) {
+		case int:
+			propertyType = propertyTypeInt
+		case int8:
+			propertyType = propertyTypeInt8
+		case int16:
+			propertyType = propertyTypeInt16
+		case int32:
+			propertyType = propertyTypeInt32
+		case int64:
+			propertyType = propertyTypeInt64
+		case uint8:
+			propertyType = propertyTypeUint8
+		case uint16:
+			propertyType = propertyTypeUint16
+		case uint32:
+			propertyType = propertyTypeUint32
+		case uint64:
+			propertyType = propertyTypeUint64
+		case float32:
+			propertyType = propertyTypeFloat32
+		case float64:
+			propertyType = propertyTypeFloat64
+		case time.Time:
+			propertyType = propertyTypeTime
+		case []byte:
+			propertyType = propertyTypeBytes
+		default:
+			continue
+		}
+		types[name] = propertyType
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	return types
+}
+
+// restorePropertyTypes converts properties decoded with json.Decoder.UseNumber back to their recorded types.
+// Numbers without a recorded type, e.g. in exports written before types were recorded, become int64 when they
+// are integers and float64 otherwise.
+func restorePropertyTypes(properties map[string]any, types map[string]string) error {
+	for name, value := range properties {
+		restored, err := restorePropertyType(value, types[name])
+		if err != nil {
+			return fmt.Errorf("application property '%s': %w", name, err)
+		}
+		properties[name] = restored
+	}
+	return nil
+}
+
+func restorePropertyType(value any, propertyType string) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		switch propertyType {
+		case propertyTypeInt:
+			n, err := strconv.ParseInt(v.String(), 10, strconv.IntSize)
+			return int(n), err
+		case propertyTypeInt8:
+			n, err := strconv.ParseInt(v.String(), 10, 8)
+			return int8(n), err
+		case propertyTypeInt16:
+			n, err := strconv.ParseInt(v.String(), 10, 16)
+			return int16(n), err
+		case propertyTypeInt32:
+			n, err := strconv.ParseInt(v.String(), 10, 32)
+			return int32(n), err
+		case propertyTypeInt64:
+			return v.Int64()
+		case propertyTypeUint8:
+			n, err := strconv.ParseUint(v.String(), 10, 8)
+			return uint8(n), err
+		case propertyTypeUint16:
+			n, err := strconv.ParseUint(v.String(), 10, 16)
+			return uint16(n), err
+		case propertyTypeUint32:
+			n, err := strconv.ParseUint(v.String(), 10, 32)
+			return uint32(n), err
+		case propertyTypeUint64:
+			return strconv.ParseUint(v.String(), 10, 64)
+		case propertyTypeFloat32:
+			n, err := strconv.ParseFloat(v.String(), 32)
+			return float32(n), err
+		case propertyTypeFloat64:
+			return v.Float64()
+		default:
+			if n, err := v.Int64(); err == nil {
+				return n, nil
+			}
+			return v.Float64()
+		}
+	case string:
+		switch propertyType {
+		case propertyTypeTime:
+			return time.Parse(time.RFC3339Nano, v)
+		case propertyTypeBytes:
+			return base64.StdEncoding.DecodeString(v)
+		}
+	}
+	// redacted properties are strings whatever their recorded type
+	return value, nil
+}
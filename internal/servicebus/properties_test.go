@@ -0,0 +1,89 @@
+package servicebus
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestorePropertyTypes(t *testing.T) {
+	enqueued := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	properties := map[string]any{
+		"count":    int64(3),
+		"attempt":  int32(2),
+		"ratio":    0.5,
+		"whole":    float64(2),
+		"flag":     uint8(1),
+		"enqueued": enqueued,
+		"raw":      []byte{0x01, 0x02},
+		"name":     "orders",
+		"ok":       true,
+	}
+	record := &DeadLetterMessage{ApplicationProperties: properties, ApplicationPropertyTypes: propertyTypes(properties)}
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("failed to encode record: %v", err)
+	}
+
+	var decoded DeadLetterMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("failed to decode record: %v", err)
+	}
+	if err := restorePropertyTypes(decoded.ApplicationProperties, decoded.ApplicationPropertyTypes); err != nil {
+		t.Fatalf("failed to restore property types: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded.ApplicationProperties, properties) {
+		t.Errorf("expected %#v, got %#v", properties, decoded.ApplicationProperties)
+	}
+}
+
+func TestRestorePropertyTypesUntyped(t *testing.T) {
+	properties := map[string]any{"count": json.Number("3"), "ratio": json.Number("0.5"), "redacted": "[REDACTED]"}
+
+	if err := restorePropertyTypes(properties, map[string]string{"redacted": propertyTypeInt64}); err != nil {
+		t.Fatalf("failed to restore property types: %v", err)
+	}
+
+	want := map[string]any{"count": int64(3), "ratio": 0.5, "redacted": "[REDACTED]"}
+	if !reflect.DeepEqual(properties, want) {
+		t.Errorf("expected %#v, got %#v", want, properties)
+	}
+}
+
+func TestDecodeImportRecord(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		allowRedacted bool
+		wantErr       string
+	}{
+		{name: "Record", line: `{"messageID":"m1","body":"aGk=","bodyEncoding":"base64","applicationProperties":{"n":1}}`},
+		{name: "Redacted", line: `{"messageID":"m1","body":"KioqKg==","bodyEncoding":"base64","redacted":true}`, wantErr: "exported with redaction"},
+		{name: "RedactedAllowed", line: `{"messageID":"m1","body":"KioqKg==","bodyEncoding":"base64","redacted":true}`, allowRedacted: true},
+		{name: "Invalid", line: `{"messageID":`, wantErr: "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received, err := decodeImportRecord([]byte(tt.line), tt.allowRedacted)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to decode record: %v", err)
+			}
+			if received.MessageID != "m1" {
+				t.Errorf("expected message m1, got %s", received.MessageID)
+			}
+		})
+	}
+}
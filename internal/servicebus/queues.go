@@ -3,41 +3,101 @@ package servicebus
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 )
 
-func purgeQueueWithOptions(ctx context.Context, client *azservicebus.Client, queue string, options *azservicebus.ReceiverOptions, queueType string) error {
-	log.Printf("creating %s receiver", queueType)
-	receiver, err := client.NewReceiverForQueue(queue, options)
+// messageReceiver is the part of Receiver and SessionReceiver used to purge messages
+type messageReceiver interface {
+	PeekMessages(ctx context.Context, maxMessageCount int, options *azservicebus.PeekMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	ReceiveDeferredMessages(ctx context.Context, sequenceNumbers []int64, options *azservicebus.ReceiveDeferredMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+}
+
+func purgeQueueWithOptions(ctx context.Context, client *azservicebus.Client, entity Entity, options *azservicebus.ReceiverOptions, queueType string) error {
+	slog.DebugContext(ctx, "creating receiver", "queue", queueType)
+	receiver, err := entity.newReceiver(client, options)
 	if err != nil {
 		return err
 	}
 	defer receiver.Close(ctx)
 
+	return purgeReceiver(ctx, receiver, queueType)
+}
+
+// purgeSessions purges a session-enabled queue or subscription one session at a time
+func purgeSessions(ctx context.Context, client *azservicebus.Client, entity Entity) error {
+	for {
+		slog.DebugContext(ctx, "accepting next session")
+		receiver, err := entity.acceptNextSession(ctx, client)
+		if err != nil {
+			return err
+		}
+		if receiver == nil {
+			slog.DebugContext(ctx, "no more sessions available")
+			return nil
+		}
+
+		err = purgeReceiver(ctx, receiver, fmt.Sprintf("session '%s'", receiver.SessionID()))
+		receiver.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func purgeReceiver(ctx context.Context, receiver messageReceiver, queueType string) error {
 	totalPurged := 0
 	batchSize := 100
 	for {
 		// check if any messages exist
-		log.Printf("checking for messages in %s", queueType)
-		peekedMessages, err := receiver.PeekMessages(ctx, 1, nil) // peek one message
+		slog.DebugContext(ctx, "checking for messages", "queue", queueType)
+		peekedMessages, err := receiver.PeekMessages(ctx, batchSize, &azservicebus.PeekMessagesOptions{
+			FromSequenceNumber: to.Ptr(int64(0)),
+		})
 		if err != nil {
 			return fmt.Errorf("failed to peek messages from %s: %w", queueType, err)
 		}
 		if len(peekedMessages) == 0 {
-			log.Printf("no messages found in %s", queueType)
+			slog.DebugContext(ctx, "no messages found", "queue", queueType)
 			break
 		}
-		log.Printf("found messages in %s", queueType)
+		slog.DebugContext(ctx, "found messages", "queue", queueType)
+
+		// deferred messages can only be received by sequence number
+		var deferredSequenceNumbers []int64
+		hasActive := false
+		for _, message := range peekedMessages {
+			if message.State == azservicebus.MessageStateDeferred {
+				deferredSequenceNumbers = append(deferredSequenceNumbers, *message.SequenceNumber)
+			} else {
+				hasActive = true
+			}
+		}
+
+		var messages []*azservicebus.ReceivedMessage
+		if len(deferredSequenceNumbers) > 0 {
+			slog.DebugContext(ctx, "receiving deferred messages", "queue", queueType, "count", len(deferredSequenceNumbers))
+			deferred, err := receiver.ReceiveDeferredMessages(ctx, deferredSequenceNumbers, nil)
+			if err != nil {
+				return fmt.Errorf("failed to receive deferred messages from %s: %w", queueType, err)
+			}
+			messages = append(messages, deferred...)
+		}
 
 		// receive messages
-		log.Printf("receiving messages from %s with batch size %d", queueType, batchSize)
-		messages, err := receiver.ReceiveMessages(ctx, batchSize, nil)
-		if err != nil {
-			return fmt.Errorf("failed to receive messages from %s: %w", queueType, err)
+		if hasActive {
+			slog.DebugContext(ctx, "receiving messages", "queue", queueType, "batchSize", batchSize)
+			received, err := receiver.ReceiveMessages(ctx, batchSize, nil)
+			if err != nil {
+				return fmt.Errorf("failed to receive messages from %s: %w", queueType, err)
+			}
+			messages = append(messages, received...)
 		}
-		log.Printf("received batch of %d messages from %s", len(messages), queueType)
+		slog.DebugContext(ctx, "received messages", "queue", queueType, "count", len(messages))
 
 		// complete received messages
 		for _, message := range messages {
@@ -48,22 +108,33 @@ func purgeQueueWithOptions(ctx context.Context, client *azservicebus.Client, que
 				totalPurged++
 			}
 		}
-		log.Printf("purged batch of %d messages from %s", len(messages), queueType)
+		slog.DebugContext(ctx, "purged message batch", "queue", queueType, "count", len(messages))
 	}
 
 	if totalPurged > 0 {
-		log.Printf("purged %d messages from %s", totalPurged, queueType)
+		slog.InfoContext(ctx, "purged messages", "queue", queueType, "count", totalPurged)
 	}
 	return nil
 }
 
-func PurgeQueue(ctx context.Context, client *azservicebus.Client, queue string) error {
-	return purgeQueueWithOptions(ctx, client, queue, nil, "queue")
+// PurgeQueue purges the queue or subscription of all active messages
+func PurgeQueue(ctx context.Context, client *azservicebus.Client, entity Entity) (err error) {
+	ctx, span := startSpan(ctx, "purge", entity)
+	defer func() { endSpan(span, err) }()
+
+	if entity.RequiresSession {
+		return purgeSessions(ctx, client, entity)
+	}
+	return purgeQueueWithOptions(ctx, client, entity, nil, "queue")
 }
 
-func PurgeDeadLetterQueue(ctx context.Context, client *azservicebus.Client, queue string) error {
+// PurgeDeadLetterQueue purges the dead-letter queue of the queue or subscription, which never requires sessions
+func PurgeDeadLetterQueue(ctx context.Context, client *azservicebus.Client, entity Entity) (err error) {
+	ctx, span := startSpan(ctx, "purge_dead_letter", entity)
+	defer func() { endSpan(span, err) }()
+
 	options := &azservicebus.ReceiverOptions{
 		SubQueue: azservicebus.SubQueueDeadLetter,
 	}
-	return purgeQueueWithOptions(ctx, client, queue, options, "dead-letter queue")
+	return purgeQueueWithOptions(ctx, client, entity, options, "dead-letter queue")
 }
@@ -0,0 +1,222 @@
+package servicebus
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"dlqt/internal/redact"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+)
+
+const (
+	// DefaultStatsBucketSize is the enqueued-time histogram bucket size when none is given
+	DefaultStatsBucketSize = time.Hour
+
+	statsPageSize = 250
+)
+
+// StatsOptions controls the peek-based scan of a dead letter queue
+type StatsOptions struct {
+	Namespace string
+	// MaxMessages stops the scan after this many messages, 0 scans the whole dead letter queue
+	MaxMessages int
+	// BucketSize of the enqueued-time histogram, 0 uses DefaultStatsBucketSize
+	BucketSize time.Duration
+	// Top keeps only the most common values of each count, 0 keeps all
+	Top int
+	// Redactor masks error descriptions before they are counted, so values masked alike share a row
+	Redactor *redact.Redactor
+}
+
+// GetRuntimeCounts reads the message counts of an entity with the admin client
+func GetRuntimeCounts(ctx context.Context, client *admin.Client, entity Entity) (_ *RuntimeCounts, err error) {
+	ctx, span := startSpan(ctx, "runtime_counts", entity)
+	defer func() { endSpan(span, err) }()
+
+	if entity.IsSubscription() {
+		resp, err := client.GetSubscriptionRuntimeProperties(ctx, entity.Topic, entity.Subscription, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get runtime properties of %s: %w", entity, err)
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("%s not found", entity)
+		}
+		return &RuntimeCounts{
+			ActiveMessageCount:             resp.ActiveMessageCount,
+			DeadLetterMessageCount:         resp.DeadLetterMessageCount,
+			TransferMessageCount:           resp.TransferMessageCount,
+			TransferDeadLetterMessageCount: resp.TransferDeadLetterMessageCount,
+			TotalMessageCount:              resp.TotalMessageCount,
+		}, nil
+	}
+
+	resp, err := client.GetQueueRuntimeProperties(ctx, entity.Queue, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get runtime properties of %s: %w", entity, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("%s not found", entity)
+	}
+	return &RuntimeCounts{
+		ActiveMessageCount:             resp.ActiveMessageCount,
+		DeadLetterMessageCount:         resp.DeadLetterMessageCount,
+		ScheduledMessageCount:          &resp.ScheduledMessageCount,
+		TransferMessageCount:           resp.TransferMessageCount,
+		TransferDeadLetterMessageCount: resp.TransferDeadLetterMessageCount,
+		TotalMessageCount:              resp.TotalMessageCount,
+		SizeInBytes:                    &resp.SizeInBytes,
+	}, nil
+}
+
+// ScanDeadLetterStats peeks through the dead letter queue and aggregates the messages, without locking them
+func ScanDeadLetterStats(ctx context.Context, client *azservicebus.Client, entity Entity, options *StatsOptions) (_ *DeadLetterStats, err error) {
+	ctx, span := startSpan(ctx, "stats", entity)
+	defer func() { endSpan(span, err) }()
+
+	if options == nil {
+		options = &StatsOptions{}
+	}
+
+	receiverOptions := &azservicebus.ReceiverOptions{
+		SubQueue: azservicebus.SubQueueDeadLetter,
+	}
+	receiver, err := entity.newReceiver(client, receiverOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DLQ receiver for %s: %w", entity, err)
+	}
+	defer receiver.Close(ctx)
+
+	aggregator := newStatsAggregator(options)
+	var from int64
+	for {
+		limit := statsPageSize
+		if options.MaxMessages > 0 {
+			if aggregator.scanned >= options.MaxMessages {
+				// only truncated when there is a message past the limit
+				next, err := peekMessages(ctx, receiver, from, 1)
+				if err != nil {
+					return nil, fmt.Errorf("failed to peek DLQ of %s: %w", entity, err)
+				}
+				aggregator.truncated = len(next) > 0
+				break
+			}
+			limit = min(limit, options.MaxMessages-aggregator.scanned)
+		}
+
+		messages, err := peekMessages(ctx, receiver, from, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to peek DLQ of %s: %w", entity, err)
+		}
+		for _, message := range messages {
+			aggregator.add(message)
+		}
+		if len(messages) < limit {
+			break
+		}
+		from = *messages[len(messages)-1].SequenceNumber + 1
+	}
+
+	stats := aggregator.stats()
+	stats.Namespace = options.Namespace
+	stats.Queue = entity.Queue
+	stats.Topic = entity.Topic
+	stats.Subscription = entity.Subscription
+	return stats, nil
+}
+
+// statsAggregator counts dead letter messages by reason, error description, subject and enqueued time
+type statsAggregator struct {
+	top                int
+	bucketSize         time.Duration
+	redactor           *redact.Redactor
+	scanned            int
+	truncated          bool
+	oldest, newest     *time.Time
+	byReason           map[string]int
+	byErrorDescription map[string]int
+	bySubject          map[string]int
+	byEnqueuedTime     map[time.Time]int
+}
+
+func newStatsAggregator(options *StatsOptions) *statsAggregator {
+	bucketSize := options.BucketSize
+	if bucketSize <= 0 {
+		bucketSize = DefaultStatsBucketSize
+	}
+	return &statsAggregator{
+		top:                options.Top,
+		bucketSize:         bucketSize,
+		redactor:           options.Redactor,
+		byReason:           map[string]int{},
+		byErrorDescription: map[string]int{},
+		bySubject:          map[string]int{},
+		byEnqueuedTime:     map[time.Time]int{},
+	}
+}
+
+func (a *statsAggregator) add(message *azservicebus.ReceivedMessage) {
+	a.scanned++
+	a.byReason[stringValue(message.DeadLetterReason)]++
+	a.byErrorDescription[a.redactor.Text(stringValue(message.DeadLetterErrorDescription))]++
+	a.bySubject[stringValue(message.Subject)]++
+
+	if message.EnqueuedTime == nil {
+		return
+	}
+	enqueued := message.EnqueuedTime.UTC()
+	a.byEnqueuedTime[enqueued.Truncate(a.bucketSize)]++
+	if a.oldest == nil || enqueued.Before(*a.oldest) {
+		a.oldest = &enqueued
+	}
+	if a.newest == nil || enqueued.After(*a.newest) {
+		a.newest = &enqueued
+	}
+}
+
+func (a *statsAggregator) stats() *DeadLetterStats {
+	stats := &DeadLetterStats{
+		Scanned:            a.scanned,
+		Truncated:          a.truncated,
+		OldestEnqueuedTime: a.oldest,
+		NewestEnqueuedTime: a.newest,
+		BucketSize:         a.bucketSize.String(),
+		ByReason:           topCounts(a.byReason, a.top),
+		ByErrorDescription: topCounts(a.byErrorDescription, a.top),
+		BySubject:          topCounts(a.bySubject, a.top),
+		ByEnqueuedTime:     make([]TimeBucket, 0, len(a.byEnqueuedTime)),
+	}
+	for start, count := range a.byEnqueuedTime {
+		stats.ByEnqueuedTime = append(stats.ByEnqueuedTime, TimeBucket{Start: start, Count: count})
+	}
+	slices.SortFunc(stats.ByEnqueuedTime, func(x, y TimeBucket) int {
+		return x.Start.Compare(y.Start)
+	})
+	return stats
+}
+
+// topCounts sorts counts by descending count then value, keeping the top n when n is positive
+func topCounts(counts map[string]int, n int) []ValueCount {
+	values := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, ValueCount{Value: value, Count: count})
+	}
+	slices.SortFunc(values, func(x, y ValueCount) int {
+		return cmp.Or(cmp.Compare(y.Count, x.Count), cmp.Compare(x.Value, y.Value))
+	})
+	if n > 0 && len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,68 @@
+package servicebus
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"dlqt/internal/redact"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestStatsAggregator(t *testing.T) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	messages := []*azservicebus.ReceivedMessage{
+		{DeadLetterReason: to.Ptr("MaxDeliveryCountExceeded"), Subject: to.Ptr("order"), EnqueuedTime: to.Ptr(base.Add(5 * time.Minute))},
+		{DeadLetterReason: to.Ptr("MaxDeliveryCountExceeded"), Subject: to.Ptr("invoice"), EnqueuedTime: to.Ptr(base.Add(50 * time.Minute))},
+		{DeadLetterReason: to.Ptr("TTLExpiredException"), DeadLetterErrorDescription: to.Ptr("expired"), EnqueuedTime: to.Ptr(base.Add(2 * time.Hour))},
+		{DeadLetterReason: to.Ptr("MaxDeliveryCountExceeded"), Subject: to.Ptr("order")},
+	}
+
+	aggregator := newStatsAggregator(&StatsOptions{Top: 2})
+	for _, message := range messages {
+		aggregator.add(message)
+	}
+	stats := aggregator.stats()
+
+	if stats.Scanned != len(messages) {
+		t.Errorf("expected %d scanned, got %d", len(messages), stats.Scanned)
+	}
+	if want := []ValueCount{{"MaxDeliveryCountExceeded", 3}, {"TTLExpiredException", 1}}; !slices.Equal(stats.ByReason, want) {
+		t.Errorf("expected reasons %v, got %v", want, stats.ByReason)
+	}
+	if want := []ValueCount{{"order", 2}, {"", 1}}; !slices.Equal(stats.BySubject, want) {
+		t.Errorf("expected top 2 subjects %v, got %v", want, stats.BySubject)
+	}
+	if want := []TimeBucket{{base, 2}, {base.Add(2 * time.Hour), 1}}; !slices.Equal(stats.ByEnqueuedTime, want) {
+		t.Errorf("expected buckets %v, got %v", want, stats.ByEnqueuedTime)
+	}
+	if stats.OldestEnqueuedTime == nil || !stats.OldestEnqueuedTime.Equal(base.Add(5*time.Minute)) {
+		t.Errorf("expected oldest %v, got %v", base.Add(5*time.Minute), stats.OldestEnqueuedTime)
+	}
+	if stats.NewestEnqueuedTime == nil || !stats.NewestEnqueuedTime.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("expected newest %v, got %v", base.Add(2*time.Hour), stats.NewestEnqueuedTime)
+	}
+	if stats.BucketSize != DefaultStatsBucketSize.String() {
+		t.Errorf("expected bucket size %s, got %s", DefaultStatsBucketSize, stats.BucketSize)
+	}
+}
+
+func TestStatsAggregatorRedacted(t *testing.T) {
+	redactor, err := redact.New(&redact.Rules{Patterns: []string{`order \d+`}})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	aggregator := newStatsAggregator(&StatsOptions{Redactor: redactor})
+	for _, description := range []string{"order 1 failed", "order 2 failed", "timeout"} {
+		aggregator.add(&azservicebus.ReceivedMessage{DeadLetterErrorDescription: to.Ptr(description)})
+	}
+	stats := aggregator.stats()
+
+	want := []ValueCount{{redact.Mask + " failed", 2}, {"timeout", 1}}
+	if !slices.Equal(stats.ByErrorDescription, want) {
+		t.Errorf("expected error descriptions %v, got %v", want, stats.ByErrorDescription)
+	}
+}
@@ -0,0 +1,50 @@
+package servicebus
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// application properties carrying the trace context of a retrigger, Diagnostic-Id is the Azure SDK's name for traceparent
+const (
+	TraceParentProperty  = "traceparent"
+	DiagnosticIDProperty = "Diagnostic-Id"
+)
+
+var tracer = otel.Tracer("dlqt/internal/servicebus")
+
+// startSpan starts a client span for an operation on an entity, end it with endSpan
+func startSpan(ctx context.Context, operation string, entity Entity) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "servicebus."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "servicebus"),
+			attribute.String("messaging.operation.name", operation),
+			attribute.String("messaging.destination.name", entity.path()),
+		),
+	)
+}
+
+// endSpan records a failed operation's error and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext sets the traceparent and Diagnostic-Id properties from the span in ctx, if it's recording a trace
+func injectTraceContext(ctx context.Context, properties map[string]any) {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	if traceParent := carrier.Get("traceparent"); traceParent != "" {
+		properties[TraceParentProperty] = traceParent
+		properties[DiagnosticIDProperty] = traceParent
+	}
+}
@@ -0,0 +1,33 @@
+package servicebus
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectTraceContext(t *testing.T) {
+	t.Run("Span", func(t *testing.T) {
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			TraceFlags: trace.FlagsSampled,
+		})
+		properties := map[string]any{}
+		injectTraceContext(trace.ContextWithSpanContext(context.Background(), spanContext), properties)
+
+		want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		if properties[TraceParentProperty] != want || properties[DiagnosticIDProperty] != want {
+			t.Errorf("expected trace context %s, got %v", want, properties)
+		}
+	})
+
+	t.Run("NoSpan", func(t *testing.T) {
+		properties := map[string]any{}
+		injectTraceContext(context.Background(), properties)
+		if len(properties) != 0 {
+			t.Errorf("expected no trace context, got %v", properties)
+		}
+	})
+}
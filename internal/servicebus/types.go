@@ -1,15 +1,34 @@
 package servicebus
 
 import (
+	"encoding/base64"
+	"fmt"
 	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// encodings of DeadLetterMessage.Body, an empty encoding is utf8
+const (
+	BodyEncodingUTF8   = "utf8"
+	BodyEncodingBase64 = "base64"
 )
 
 // JSON-serializable version of a Service Bus dead letter message
 type DeadLetterMessage struct {
 	Namespace                  string         `json:"namespace"`
-	Queue                      string         `json:"queue"`
+	Queue                      string         `json:"queue,omitempty"`
+	Topic                      string         `json:"topic,omitempty"`
+	Subscription               string         `json:"subscription,omitempty"`
 	MessageID                  string         `json:"messageID"`
 	Body                       string         `json:"body"`
+	BodyEncoding               string         `json:"bodyEncoding,omitempty"`
+	BodySize                   int            `json:"bodySize"`
+	BodyTruncated              bool           `json:"bodyTruncated,omitempty"`
+	DecodedBody                string         `json:"decodedBody,omitempty"`
+	DecodedBodyTruncated       bool           `json:"decodedBodyTruncated,omitempty"`
+	Decoders                   []string       `json:"decoders,omitempty"`
+	DecodeError                string         `json:"decodeError,omitempty"`
 	ContentType                *string        `json:"contentType,omitempty"`
 	CorrelationID              *string        `json:"correlationID,omitempty"`
 	DeadLetterErrorDescription *string        `json:"deadLetterErrorDescription,omitempty"`
@@ -31,4 +50,156 @@ type DeadLetterMessage struct {
 	TimeToLive                 *time.Duration `json:"timeToLive,omitempty"`
 	To                         *string        `json:"to,omitempty"`
 	ApplicationProperties      map[string]any `json:"applicationProperties,omitempty"`
+	// ApplicationPropertyTypes records the Go type of non-string properties in exports, so an import restores the
+	// AMQP types JSON loses, e.g. an int64 property would otherwise be resent as a double
+	ApplicationPropertyTypes map[string]string `json:"applicationPropertyTypes,omitempty"`
+	// Redacted marks exports written with redaction, their masked values must not be imported as real messages
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// JSON-serializable page of dead letter messages, Next is the sequence number to continue peeking from
+type DeadLetterMessagePage struct {
+	Messages []*DeadLetterMessage `json:"messages"`
+	Next     *int64               `json:"next,omitempty"`
+}
+
+// JSON-serializable reference to a dead letter message
+type MessageReference struct {
+	MessageID        string  `json:"messageID"`
+	SequenceNumber   int64   `json:"sequenceNumber"`
+	DeadLetterReason *string `json:"deadLetterReason,omitempty"`
+}
+
+// JSON-serializable result of a bulk retrigger
+type BulkRetriggerResult struct {
+	DryRun      bool               `json:"dryRun"`
+	Matched     []MessageReference `json:"matched"`
+	Retriggered int                `json:"retriggered"`
+}
+
+// JSON-serializable runtime counts of a queue or subscription, scheduled count and size are only reported for queues
+type RuntimeCounts struct {
+	ActiveMessageCount             int32  `json:"activeMessageCount"`
+	DeadLetterMessageCount         int32  `json:"deadLetterMessageCount"`
+	ScheduledMessageCount          *int32 `json:"scheduledMessageCount,omitempty"`
+	TransferMessageCount           int32  `json:"transferMessageCount"`
+	TransferDeadLetterMessageCount int32  `json:"transferDeadLetterMessageCount"`
+	TotalMessageCount              int64  `json:"totalMessageCount"`
+	SizeInBytes                    *int64 `json:"sizeInBytes,omitempty"`
+}
+
+// JSON-serializable number of dead letter messages sharing a value
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// JSON-serializable number of dead letter messages enqueued in [Start, Start+bucket size)
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// JSON-serializable statistics of a dead letter queue, Truncated means the scan stopped before the end of the DLQ
+type DeadLetterStats struct {
+	Namespace          string         `json:"namespace"`
+	Queue              string         `json:"queue,omitempty"`
+	Topic              string         `json:"topic,omitempty"`
+	Subscription       string         `json:"subscription,omitempty"`
+	Runtime            *RuntimeCounts `json:"runtime,omitempty"`
+	Scanned            int            `json:"scanned"`
+	Truncated          bool           `json:"truncated,omitempty"`
+	OldestEnqueuedTime *time.Time     `json:"oldestEnqueuedTime,omitempty"`
+	NewestEnqueuedTime *time.Time     `json:"newestEnqueuedTime,omitempty"`
+	BucketSize         string         `json:"bucketSize"`
+	ByReason           []ValueCount   `json:"byReason"`
+	ByErrorDescription []ValueCount   `json:"byErrorDescription"`
+	BySubject          []ValueCount   `json:"bySubject"`
+	ByEnqueuedTime     []TimeBucket   `json:"byEnqueuedTime"`
+}
+
+// NewDeadLetterMessage maps a received Service Bus message to a DeadLetterMessage, rendering the body according to the options
+func NewDeadLetterMessage(namespace string, entity Entity, message *azservicebus.ReceivedMessage, options *BodyOptions) *DeadLetterMessage {
+	deadLetterMessage := &DeadLetterMessage{
+		Namespace:                  namespace,
+		Queue:                      entity.Queue,
+		Topic:                      entity.Topic,
+		Subscription:               entity.Subscription,
+		MessageID:                  message.MessageID,
+		ContentType:                message.ContentType,
+		CorrelationID:              message.CorrelationID,
+		DeadLetterErrorDescription: message.DeadLetterErrorDescription,
+		DeadLetterReason:           message.DeadLetterReason,
+		DeadLetterSource:           message.DeadLetterSource,
+		DeliveryCount:              message.DeliveryCount,
+		EnqueuedSequenceNumber:     message.EnqueuedSequenceNumber,
+		EnqueuedTime:               message.EnqueuedTime,
+		ExpiresAt:                  message.ExpiresAt,
+		LockedUntil:                message.LockedUntil,
+		PartitionKey:               message.PartitionKey,
+		ReplyTo:                    message.ReplyTo,
+		ReplyToSessionID:           message.ReplyToSessionID,
+		ScheduledEnqueueTime:       message.ScheduledEnqueueTime,
+		SequenceNumber:             message.SequenceNumber,
+		SessionID:                  message.SessionID,
+		State:                      int32(message.State),
+		Subject:                    message.Subject,
+		TimeToLive:                 message.TimeToLive,
+		To:                         message.To,
+		ApplicationProperties:      message.ApplicationProperties,
+	}
+	deadLetterMessage.setBody(message.Body, message.ContentType, options)
+	if options != nil {
+		deadLetterMessage.redactMetadata(options.Redactor)
+	}
+	return deadLetterMessage
+}
+
+// DecodeBody returns the raw body bytes according to BodyEncoding
+func (m *DeadLetterMessage) DecodeBody() ([]byte, error) {
+	switch m.BodyEncoding {
+	case "", BodyEncodingUTF8:
+		return []byte(m.Body), nil
+	case BodyEncodingBase64:
+		return base64.StdEncoding.DecodeString(m.Body)
+	default:
+		return nil, fmt.Errorf("unknown body encoding '%s'", m.BodyEncoding)
+	}
+}
+
+// ReceivedMessage maps a DeadLetterMessage back to a received Service Bus message, e.g. to resend an exported record
+func (m *DeadLetterMessage) ReceivedMessage() (*azservicebus.ReceivedMessage, error) {
+	if m.BodyTruncated {
+		return nil, fmt.Errorf("body of message '%s' is truncated, full size is %d bytes", m.MessageID, m.BodySize)
+	}
+	body, err := m.DecodeBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return &azservicebus.ReceivedMessage{
+		MessageID:                  m.MessageID,
+		Body:                       body,
+		ContentType:                m.ContentType,
+		CorrelationID:              m.CorrelationID,
+		DeadLetterErrorDescription: m.DeadLetterErrorDescription,
+		DeadLetterReason:           m.DeadLetterReason,
+		DeadLetterSource:           m.DeadLetterSource,
+		DeliveryCount:              m.DeliveryCount,
+		EnqueuedSequenceNumber:     m.EnqueuedSequenceNumber,
+		EnqueuedTime:               m.EnqueuedTime,
+		ExpiresAt:                  m.ExpiresAt,
+		LockedUntil:                m.LockedUntil,
+		PartitionKey:               m.PartitionKey,
+		ReplyTo:                    m.ReplyTo,
+		ReplyToSessionID:           m.ReplyToSessionID,
+		ScheduledEnqueueTime:       m.ScheduledEnqueueTime,
+		SequenceNumber:             m.SequenceNumber,
+		SessionID:                  m.SessionID,
+		State:                      azservicebus.MessageState(m.State),
+		Subject:                    m.Subject,
+		TimeToLive:                 m.TimeToLive,
+		To:                         m.To,
+		ApplicationProperties:      m.ApplicationProperties,
+	}, nil
 }
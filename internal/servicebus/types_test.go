@@ -0,0 +1,76 @@
+package servicebus
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+func TestDeadLetterMessageDecodeBody(t *testing.T) {
+	binary := []byte{0x00, 0xff, 0xfe, 'a'}
+
+	tests := []struct {
+		name    string
+		message DeadLetterMessage
+		want    []byte
+		wantErr bool
+	}{
+		{name: "Default", message: DeadLetterMessage{Body: "plain"}, want: []byte("plain")},
+		{name: "UTF8", message: DeadLetterMessage{Body: "plain", BodyEncoding: BodyEncodingUTF8}, want: []byte("plain")},
+		{name: "Base64", message: DeadLetterMessage{Body: base64.StdEncoding.EncodeToString(binary), BodyEncoding: BodyEncodingBase64}, want: binary},
+		{name: "InvalidBase64", message: DeadLetterMessage{Body: "%%%", BodyEncoding: BodyEncodingBase64}, wantErr: true},
+		{name: "UnknownEncoding", message: DeadLetterMessage{Body: "plain", BodyEncoding: "hex"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.message.DecodeBody()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if string(got) != string(tt.want) {
+				t.Errorf("expected body %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeadLetterMessageReceivedMessage(t *testing.T) {
+	sequenceNumber := int64(42)
+	message := &DeadLetterMessage{
+		MessageID:             "id",
+		Body:                  base64.StdEncoding.EncodeToString([]byte("body")),
+		BodyEncoding:          BodyEncodingBase64,
+		SequenceNumber:        &sequenceNumber,
+		State:                 int32(azservicebus.MessageStateDeferred),
+		ApplicationProperties: map[string]any{"tenant": "a"},
+	}
+
+	received, err := message.ReceivedMessage()
+	if err != nil {
+		t.Fatalf("failed to convert message: %v", err)
+	}
+	if received.MessageID != "id" {
+		t.Errorf("expected message ID %q, got %q", "id", received.MessageID)
+	}
+	if string(received.Body) != "body" {
+		t.Errorf("expected body %q, got %q", "body", received.Body)
+	}
+	if received.SequenceNumber == nil || *received.SequenceNumber != sequenceNumber {
+		t.Errorf("expected sequence number %d, got %v", sequenceNumber, received.SequenceNumber)
+	}
+	if received.State != azservicebus.MessageStateDeferred {
+		t.Errorf("expected state %v, got %v", azservicebus.MessageStateDeferred, received.State)
+	}
+	if received.ApplicationProperties["tenant"] != "a" {
+		t.Errorf("expected tenant property %q, got %v", "a", received.ApplicationProperties["tenant"])
+	}
+}
+
+func TestDeadLetterMessageReceivedMessageTruncated(t *testing.T) {
+	message := &DeadLetterMessage{MessageID: "id", Body: "par", BodySize: 10, BodyTruncated: true}
+	if _, err := message.ReceivedMessage(); err == nil {
+		t.Error("expected error for truncated body")
+	}
+}